@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+//Sources of public randomness.
+const (
+	BeaconDrand = "drand"
+	BeaconNIST  = "nist"
+)
+
+//BeaconRound is one output of a public randomness beacon.
+type BeaconRound struct {
+	Source     string `json:"source"`
+	Chain      uint64 `json:"chain,omitempty"`
+	Round      uint64 `json:"round"`
+	Randomness []byte `json:"randomness"`
+	Signature  []byte `json:"signature"`
+}
+
+//FetchDrand fetches a round from a drand HTTP endpoint such as https://api.drand.sh,
+//or the latest round if round is 0, using client. A nil client means
+//http.DefaultClient.
+func FetchDrand(ctx context.Context, client *http.Client, baseURL string, round uint64) (*BeaconRound, error) {
+	path := "/public/latest"
+	if round != 0 {
+		path = fmt.Sprintf("/public/%d", round)
+	}
+	var r struct {
+		Round      uint64 `json:"round"`
+		Randomness string `json:"randomness"`
+		Signature  string `json:"signature"`
+	}
+	if err := fetchJSON(ctx, client, baseURL+path, &r); err != nil {
+		return nil, err
+	}
+	if round != 0 && r.Round != round {
+		return nil, fmt.Errorf("error: drand returned round %d for round %d", r.Round, round)
+	}
+	b := &BeaconRound{Source: BeaconDrand, Round: r.Round}
+	var err error
+	if b.Randomness, err = hex.DecodeString(r.Randomness); err != nil {
+		return nil, err
+	}
+	if b.Signature, err = hex.DecodeString(r.Signature); err != nil {
+		return nil, err
+	}
+	return b, b.Check()
+}
+
+//FetchNIST fetches a pulse from a NIST beacon 2.0 endpoint such as
+//https://beacon.nist.gov/beacon/2.0, using client. A nil client means
+//http.DefaultClient.
+func FetchNIST(ctx context.Context, client *http.Client, baseURL string, chain, pulse uint64) (*BeaconRound, error) {
+	var r struct {
+		Pulse struct {
+			ChainIndex     uint64 `json:"chainIndex"`
+			PulseIndex     uint64 `json:"pulseIndex"`
+			OutputValue    string `json:"outputValue"`
+			SignatureValue string `json:"signatureValue"`
+		} `json:"pulse"`
+	}
+	if err := fetchJSON(ctx, client, fmt.Sprintf("%s/chain/%d/pulse/%d", baseURL, chain, pulse), &r); err != nil {
+		return nil, err
+	}
+	if r.Pulse.ChainIndex != chain || r.Pulse.PulseIndex != pulse {
+		return nil, fmt.Errorf("error: NIST beacon returned pulse %d/%d for pulse %d/%d", r.Pulse.ChainIndex, r.Pulse.PulseIndex, chain, pulse)
+	}
+	b := &BeaconRound{Source: BeaconNIST, Chain: r.Pulse.ChainIndex, Round: r.Pulse.PulseIndex}
+	var err error
+	if b.Randomness, err = hex.DecodeString(r.Pulse.OutputValue); err != nil {
+		return nil, err
+	}
+	if b.Signature, err = hex.DecodeString(r.Pulse.SignatureValue); err != nil {
+		return nil, err
+	}
+	return b, b.Check()
+}
+
+//fetchJSON gets url with client, or http.DefaultClient if it is nil, and decodes its
+//JSON body into v.
+func fetchJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error: %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+//Check performs the consistency checks possible without the beacon's public key: for
+//drand the randomness must be the SHA-256 of the signature. Anyone can make up a round
+//that passes them, so verifiers confirm rounds with a trusted BeaconEndpoint instead of
+//trusting the round they are handed.
+func (b *BeaconRound) Check() error {
+	if len(b.Randomness) == 0 {
+		return errors.New("error: beacon round has no randomness")
+	}
+	switch b.Source {
+	case BeaconDrand:
+		sum := sha256.Sum256(b.Signature)
+		if !bytes.Equal(sum[:], b.Randomness) {
+			return fmt.Errorf("error: drand round %d: randomness is not the hash of the signature", b.Round)
+		}
+	case BeaconNIST:
+	default:
+		return fmt.Errorf("error: unknown beacon source %q", b.Source)
+	}
+	return nil
+}
+
+//BeaconEndpoint is a beacon endpoint trusted by a verifier, for instance
+//https://api.drand.sh for drand. Rounds handed to the verifier are fetched again from it
+//and must match.
+type BeaconEndpoint struct {
+	Source string
+	URL    string
+	//Client is used to fetch rounds; nil means http.DefaultClient.
+	Client *http.Client
+}
+
+//Fetch fetches round of chain from the endpoint. chain is ignored for drand.
+func (e BeaconEndpoint) Fetch(ctx context.Context, chain, round uint64) (*BeaconRound, error) {
+	switch e.Source {
+	case BeaconDrand:
+		if round == 0 {
+			return nil, errors.New("error: drand rounds start at 1")
+		}
+		return FetchDrand(ctx, e.Client, e.URL, round)
+	case BeaconNIST:
+		return FetchNIST(ctx, e.Client, e.URL, chain, round)
+	}
+	return nil, fmt.Errorf("error: unknown beacon source %q", e.Source)
+}
+
+//Confirm checks that b is the round published by the endpoint.
+func (e BeaconEndpoint) Confirm(ctx context.Context, b *BeaconRound) error {
+	if b.Source != e.Source {
+		return fmt.Errorf("error: round is from %q, endpoint is %q", b.Source, e.Source)
+	}
+	want, err := e.Fetch(ctx, b.Chain, b.Round)
+	if err != nil {
+		return err
+	}
+	if want.Chain != b.Chain || !bytes.Equal(want.Randomness, b.Randomness) || !bytes.Equal(want.Signature, b.Signature) {
+		return fmt.Errorf("error: %s round %d does not match the published round", b.Source, b.Round)
+	}
+	return nil
+}
+
+//seed returns the bytes that bind the beacon round into derived randomness.
+func (b *BeaconRound) seed() []byte {
+	s := []byte(b.Source)
+	s = binary.BigEndian.AppendUint64(s, b.Chain)
+	s = binary.BigEndian.AppendUint64(s, b.Round)
+	return append(s, b.Randomness...)
+}
+
+//SampleIndexes derives k distinct leaf indexes in [0, n) from root and a beacon round.
+//The round must be one produced after root was published, so the publisher could not
+//have chosen the tree knowing which leaves would be audited.
+func SampleIndexes(root []byte, b *BeaconRound, n, k int) ([]int, error) {
+	if err := b.Check(); err != nil {
+		return nil, err
+	}
+	if n < 0 || k < 0 || k > n {
+		return nil, fmt.Errorf("error: cannot sample %d of %d leaves", k, n)
+	}
+	r := newDetRand(root, b.seed())
+	//partial Fisher-Yates shuffle, storing only the swapped positions
+	swapped := make(map[int]int)
+	at := func(i int) int {
+		if v, ok := swapped[i]; ok {
+			return v
+		}
+		return i
+	}
+	indexes := make([]int, 0, k)
+	for i := 0; i < k; i++ {
+		j := i + int(r.uniform(uint64(n-i)))
+		vi, vj := at(i), at(j)
+		swapped[i], swapped[j] = vj, vi
+		indexes = append(indexes, vj)
+	}
+	return indexes, nil
+}
+
+//BeaconAudit records an audit sample of a tree drawn from a beacon round.
+type BeaconAudit struct {
+	Version int         `json:"version"`
+	Root    []byte      `json:"root"`
+	Leaves  int         `json:"leaves"`
+	Beacon  BeaconRound `json:"beacon"`
+	Indexes []int       `json:"indexes"`
+}
+
+//UnmarshalJSON decodes an audit of any version up to BeaconAuditVersion.
+func (a *BeaconAudit) UnmarshalJSON(b []byte) error {
+	type beaconAudit BeaconAudit
+	return unmarshalVersioned(b, (*beaconAudit)(a), func(v *beaconAudit) *int { return &v.Version }, "beacon audit", BeaconAuditVersion)
+}
+
+//NewBeaconAudit draws k leaves of m to audit with the beacon round b.
+func NewBeaconAudit(m *MerkleTree, b *BeaconRound, k int) (*BeaconAudit, error) {
+	n := m.leafCount()
+	indexes, err := SampleIndexes(m.MerkleRoot(), b, n, k)
+	if err != nil {
+		return nil, err
+	}
+	return &BeaconAudit{Version: BeaconAuditVersion, Root: m.MerkleRoot(), Leaves: n, Beacon: *b, Indexes: indexes}, nil
+}
+
+//Verify checks that the audit is of the published root of a tree of size leaves, that its
+//beacon round was published by e and that the indexes were derived from the root and
+//the round.
+func (a *BeaconAudit) Verify(ctx context.Context, e BeaconEndpoint, root []byte, size int) error {
+	if !bytes.Equal(a.Root, root) || a.Leaves != size {
+		return fmt.Errorf("error: audit is of a tree of %d leaves with root %x, not %d leaves with root %x", a.Leaves, a.Root, size, root)
+	}
+	if err := e.Confirm(ctx, &a.Beacon); err != nil {
+		return err
+	}
+	indexes, err := SampleIndexes(a.Root, &a.Beacon, a.Leaves, len(a.Indexes))
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(indexes, a.Indexes) {
+		return errors.New("error: audit indexes were not derived from the beacon round")
+	}
+	return nil
+}
+
+//ShuffleWithBeacon returns the permutation of the committed items selected by the
+//beacon round. The round must be produced after the commitment was published.
+func (c Commitment) ShuffleWithBeacon(b *BeaconRound) ([]int, error) {
+	if err := b.Check(); err != nil {
+		return nil, err
+	}
+	return c.Shuffle(b.seed()), nil
+}
+
+//VerifyShuffleWithBeacon checks that b was published by e and that perm is the
+//selection made by ShuffleWithBeacon.
+func (c Commitment) VerifyShuffleWithBeacon(ctx context.Context, e BeaconEndpoint, b *BeaconRound, perm []int) error {
+	if err := e.Confirm(ctx, b); err != nil {
+		return err
+	}
+	want, err := c.ShuffleWithBeacon(b)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(want, perm) {
+		return errors.New("error: selection was not derived from the beacon round")
+	}
+	return nil
+}
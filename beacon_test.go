@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//testDrand serves drand rounds whose signature is derived from the round number. If
+//lie is set, it answers every request with that round instead.
+func testDrand(t *testing.T, lie uint64) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var round uint64
+		if _, err := fmt.Sscanf(r.URL.Path, "/public/%d", &round); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if lie != 0 {
+			round = lie
+		}
+		sig := sha256.Sum256([]byte(fmt.Sprint("signature ", round)))
+		rnd := sha256.Sum256(sig[:])
+		fmt.Fprintf(w, `{"round":%d,"randomness":"%s","signature":"%s"}`, round, hex.EncodeToString(rnd[:]), hex.EncodeToString(sig[:]))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testAuditTree(t *testing.T, n int) *MerkleTree {
+	t.Helper()
+	var cs []Content
+	for i := 0; i < n; i++ {
+		cs = append(cs, TestContent{x: fmt.Sprint("leaf ", i)})
+	}
+	m, err := NewTree(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestFetchDrandChecksRound(t *testing.T) {
+	ctx := context.Background()
+	srv := testDrand(t, 0)
+	b, err := FetchDrand(ctx, srv.Client(), srv.URL, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Round != 42 {
+		t.Errorf("round = %d, want 42", b.Round)
+	}
+	liar := testDrand(t, 7)
+	if _, err := FetchDrand(ctx, liar.Client(), liar.URL, 42); err == nil {
+		t.Error("round 7 accepted for round 42")
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := FetchDrand(canceled, srv.Client(), srv.URL, 42); err == nil {
+		t.Error("fetch with a canceled context succeeded")
+	}
+}
+
+func TestBeaconAuditVerify(t *testing.T) {
+	ctx := context.Background()
+	srv := testDrand(t, 0)
+	e := BeaconEndpoint{Source: BeaconDrand, URL: srv.URL, Client: srv.Client()}
+	m := testAuditTree(t, 20)
+	b, err := e.Fetch(ctx, 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewBeaconAudit(m, b, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Verify(ctx, e, m.MerkleRoot(), 20); err != nil {
+		t.Fatal(err)
+	}
+
+	//a made-up round passes Check but not the endpoint
+	sig := sha256.Sum256([]byte("chosen by the auditee"))
+	rnd := sha256.Sum256(sig[:])
+	forged := &BeaconRound{Source: BeaconDrand, Round: 1000, Randomness: rnd[:], Signature: sig[:]}
+	fa, err := NewBeaconAudit(m, forged, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fa.Verify(ctx, e, m.MerkleRoot(), 20); err == nil || !strings.Contains(err.Error(), "published round") {
+		t.Errorf("audit with a made-up round: got %v", err)
+	}
+
+	//sampling from fewer leaves than the tree has
+	small := *a
+	small.Leaves = 10
+	if err := small.Verify(ctx, e, m.MerkleRoot(), 20); err == nil {
+		t.Error("audit of 10 leaves verifies for a tree of 20")
+	}
+	other := testAuditTree(t, 21)
+	if err := a.Verify(ctx, e, other.MerkleRoot(), 20); err == nil {
+		t.Error("audit verifies against another root")
+	}
+}
+
+func TestBeaconEndpointNilClient(t *testing.T) {
+	ctx := context.Background()
+	srv := testDrand(t, 0)
+	e := BeaconEndpoint{Source: BeaconDrand, URL: srv.URL}
+	b, err := e.Fetch(ctx, 0, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Confirm(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FetchDrand(ctx, nil, srv.URL, 42); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//Canonicalizer rewrites the raw bytes of a leaf into a canonical form before it is hashed,
+//so that producers that encode the same record differently still agree on the root.
+//Steps that need packages outside the standard library, such as Unicode NFC
+//normalization with golang.org/x/text/unicode/norm, can be plugged in as custom steps.
+type Canonicalizer func([]byte) ([]byte, error)
+
+//RawContent is a Content that exposes the bytes it represents. Trees built with
+//WithCanonicalizer hash the canonicalized bytes instead of calling CalculateHash.
+type RawContent interface {
+	Content
+	Raw() ([]byte, error)
+}
+
+//WithCanonicalizer returns an Option that runs steps, in order, over the raw bytes of
+//every leaf before it is hashed. All contents of the tree must implement RawContent.
+func WithCanonicalizer(steps ...Canonicalizer) Option {
+	return func(t *MerkleTree) error {
+		t.canonicalizer = Chain(steps...)
+		return nil
+	}
+}
+
+//Chain returns a Canonicalizer that applies steps in order.
+func Chain(steps ...Canonicalizer) Canonicalizer {
+	return func(b []byte) ([]byte, error) {
+		var err error
+		for _, step := range steps {
+			if b, err = step(b); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+}
+
+//HashContent returns the leaf hash of c as computed by the tree, applying its
+//canonicalizer if one is configured.
+func (m *MerkleTree) HashContent(c Content) ([]byte, error) {
+	if m.canonicalizer == nil {
+		return c.CalculateHash()
+	}
+	rc, ok := c.(RawContent)
+	if !ok {
+		return nil, fmt.Errorf("error: content %v does not implement RawContent", c)
+	}
+	raw, err := rc.Raw()
+	if err != nil {
+		return nil, err
+	}
+	b, err := m.canonicalizer(raw)
+	if err != nil {
+		return nil, err
+	}
+	return m.hasher.Leaf(b)
+}
+
+//TrimSpace removes leading and trailing white space.
+func TrimSpace(b []byte) ([]byte, error) {
+	return bytes.TrimSpace(b), nil
+}
+
+//LowercaseKeys lowercases the keys of every object in a JSON document. Two keys of the
+//same object that only differ in case are an error. The output is compact JSON.
+func LowercaseKeys(b []byte) ([]byte, error) {
+	v, err := decodeJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	if v, err = lowercaseKeys(v); err != nil {
+		return nil, err
+	}
+	return encodeJSON(v)
+}
+
+//CanonicalJSON re-encodes a JSON document in canonical form: compact, object keys
+//sorted, no HTML escaping, and every number in a single exact form ("1.0", "1e0" and
+//"10e-1" become "1", "-0.0" becomes "0").
+func CanonicalJSON(b []byte) ([]byte, error) {
+	v, err := decodeJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	if v, err = canonicalNumbers(v); err != nil {
+		return nil, err
+	}
+	return encodeJSON(v)
+}
+
+//decodeJSON decodes a single JSON value, keeping numbers as json.Number.
+func decodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("error: trailing data after JSON value")
+	}
+	return v, nil
+}
+
+//encodeJSON encodes v as compact JSON with sorted object keys.
+func encodeJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func lowercaseKeys(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			lk := strings.ToLower(k)
+			if _, ok := out[lk]; ok {
+				return nil, fmt.Errorf("error: duplicate key %q after lowercasing", lk)
+			}
+			e, err := lowercaseKeys(e)
+			if err != nil {
+				return nil, err
+			}
+			out[lk] = e
+		}
+		return out, nil
+	case []interface{}:
+		for i, e := range v {
+			e, err := lowercaseKeys(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = e
+		}
+	}
+	return v, nil
+}
+
+func canonicalNumbers(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return canonicalNumber(v)
+	case map[string]interface{}:
+		for k, e := range v {
+			e, err := canonicalNumbers(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = e
+		}
+	case []interface{}:
+		for i, e := range v {
+			e, err := canonicalNumbers(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = e
+		}
+	}
+	return v, nil
+}
+
+//canonicalNumber formats n exactly, in the layout ECMAScript and RFC 8785 use for
+//numbers: significant digits without leading or trailing zeros, plain notation while
+//the decimal exponent is between -6 and 21 and exponent notation such as 1e+22 beyond,
+//and no sign on zero. Equal numbers get the same form however they are written, and
+//numbers that differ beyond the precision of a float64 stay distinct.
+func canonicalNumber(n json.Number) (json.Number, error) {
+	s, neg := strings.CutPrefix(string(n), "-")
+	mant, exp := s, "0"
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mant, exp = s[:i], s[i+1:]
+	}
+	e, err := strconv.ParseInt(exp, 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("error: invalid number %q", n)
+	}
+	whole, frac, _ := strings.Cut(mant, ".")
+	digits := whole + frac
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return "", fmt.Errorf("error: invalid number %q", n)
+	}
+	//the value is 0.digits times 10 to the power of point
+	point := int64(len(whole)) + e
+	trimmed := strings.TrimLeft(digits, "0")
+	point -= int64(len(digits) - len(trimmed))
+	digits = strings.TrimRight(trimmed, "0")
+	if digits == "" {
+		return "0", nil
+	}
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	k := int64(len(digits))
+	switch {
+	case k <= point && point <= 21:
+		b.WriteString(digits)
+		b.WriteString(strings.Repeat("0", int(point-k)))
+	case 0 < point && point <= 21:
+		b.WriteString(digits[:point])
+		b.WriteByte('.')
+		b.WriteString(digits[point:])
+	case -6 < point && point <= 0:
+		b.WriteString("0.")
+		b.WriteString(strings.Repeat("0", int(-point)))
+		b.WriteString(digits)
+	default:
+		b.WriteString(digits[:1])
+		if k > 1 {
+			b.WriteByte('.')
+			b.WriteString(digits[1:])
+		}
+		b.WriteByte('e')
+		if point > 0 {
+			b.WriteByte('+')
+		}
+		b.WriteString(strconv.FormatInt(point-1, 10))
+	}
+	return json.Number(b.String()), nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"testing"
+)
+
+//rawContent is a RawContent whose CalculateHash is the MD5 of its bytes.
+type rawContent string
+
+func (r rawContent) CalculateHash() ([]byte, error) {
+	h := md5.Sum([]byte(r))
+	return h[:], nil
+}
+
+func (r rawContent) Equals(other Content) (bool, error) {
+	o, ok := other.(rawContent)
+	return ok && o == r, nil
+}
+
+func (r rawContent) Raw() ([]byte, error) {
+	return []byte(r), nil
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"sorted keys", `{"b": 1, "a": [true, null, "x"]}`, `{"a":[true,null,"x"],"b":1}`},
+		{"no HTML escaping", `"<&>"`, `"<&>"`},
+		{"one", `[1, 1.0, 1e0, 10e-1, 0.1e1]`, `[1,1,1,1,1]`},
+		{"hundred", `[100, 1e2, 1E+2, 100.00]`, `[100,100,100,100]`},
+		{"zero", `[0, -0, -0.0, 0e10, 0.000]`, `[0,0,0,0,0]`},
+		{"1e22", `[1e22, 10000000000000000000000]`, `[1e+22,1e+22]`},
+		{"exponent threshold", `[1e21, 1e20]`, `[1e+21,100000000000000000000]`},
+		{"beyond float64", `[0.1000000000000000000001, 0.1]`, `[0.1000000000000000000001,0.1]`},
+		{"large integer", `12345678901234567890123`, `1.2345678901234567890123e+22`},
+		{"fractions", `[123.456e1, -1.50, 0.000001, 1e-7, 12e-8]`, `[1234.56,-1.5,0.000001,1e-7,1.2e-7]`},
+		{"nested", `{"a": {"c": [2.50], "b": -0}}`, `{"a":{"b":0,"c":[2.5]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalJSON([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+	for _, in := range []string{`{"a": 1} x`, `{`, ``} {
+		if _, err := CanonicalJSON([]byte(in)); err == nil {
+			t.Errorf("%q: no error", in)
+		}
+	}
+}
+
+func TestLowercaseKeys(t *testing.T) {
+	got, err := LowercaseKeys([]byte(`{"A": {"Bc": 1.0}, "d": [{"E": 2}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":{"bc":1.0},"d":[{"e":2}]}`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if _, err := LowercaseKeys([]byte(`{"a": 1, "A": 2}`)); err == nil {
+		t.Error("keys equal after lowercasing accepted")
+	}
+}
+
+func TestTrimSpace(t *testing.T) {
+	got, err := TrimSpace([]byte(" \n x y \t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "x y" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestChain(t *testing.T) {
+	c := Chain(TrimSpace, LowercaseKeys, CanonicalJSON)
+	got, err := c([]byte(" {\"B\": 1.0, \"a\": -0}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":0,"b":1}`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got, err := Chain()([]byte(" x ")); err != nil || string(got) != " x " {
+		t.Errorf("empty chain: got %q, %v", got, err)
+	}
+	fail := errors.New("fail")
+	called := false
+	c = Chain(func([]byte) ([]byte, error) { return nil, fail }, func(b []byte) ([]byte, error) {
+		called = true
+		return b, nil
+	})
+	if _, err := c([]byte("x")); err != fail || called {
+		t.Errorf("failing step: got %v, later step called %v", err, called)
+	}
+}
+
+func TestHashContent(t *testing.T) {
+	a := []Content{rawContent(`{"id": 1, "name": "a"}`), rawContent(" {\"name\":\"b\",\"id\":2.0}\n")}
+	b := []Content{rawContent(`{"id":1,"name":"a"}`), rawContent(`{"id":2,"name":"b"}`)}
+	ta, err := NewTree(a, WithCanonicalizer(TrimSpace, CanonicalJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb, err := NewTree(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ta.MerkleRoot(), tb.MerkleRoot()) {
+		t.Error("canonicalized tree differs from the tree over canonical contents")
+	}
+	h, err := ta.HashContent(a[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := md5.Sum([]byte(`{"id":2,"name":"b"}`))
+	if !bytes.Equal(h, want[:]) {
+		t.Errorf("leaf hash %x, want %x", h, want)
+	}
+	plain, err := NewTree(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(plain.MerkleRoot(), tb.MerkleRoot()) {
+		t.Error("tree without a canonicalizer canonicalized its contents")
+	}
+	if _, err := NewTree([]Content{TestContent{x: "x"}}, WithCanonicalizer(TrimSpace)); err == nil {
+		t.Error("content without Raw accepted by a canonicalizing tree")
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gaochaolyf/Go/verify"
+)
+
+//saltSize is the size of the random salt committed with every item.
+const saltSize = 16
+
+//saltedItem is a leaf of a commit-reveal tree: an item, its position in the list and the
+//salt that hides it until it is revealed.
+type saltedItem struct {
+	position int
+	salt     []byte
+	item     []byte
+}
+
+//bytes returns the encoding of the item that is hashed into the tree.
+func (s saltedItem) bytes() []byte {
+	b := []byte{'c'}
+	b = binary.AppendUvarint(b, uint64(s.position))
+	b = binary.AppendUvarint(b, uint64(len(s.salt)))
+	b = append(b, s.salt...)
+	return append(b, s.item...)
+}
+
+//CalculateHash hashes the encoding of a saltedItem
+func (s saltedItem) CalculateHash() ([]byte, error) {
+	return verify.SHA256.Leaf(s.bytes())
+}
+
+//Equals tests for equality of two saltedItems
+func (s saltedItem) Equals(other Content) (bool, error) {
+	o, ok := other.(saltedItem)
+	return ok && bytes.Equal(s.bytes(), o.bytes()), nil
+}
+
+//Commitment is the public part of a commit-reveal: the root of the tree over the salted
+//items and the number of items. The tree is hashed with verify.SHA256, so a committer
+//able to find MD5 collisions still cannot open a commitment to two different lists.
+type Commitment struct {
+	Version int    `json:"version"`
+	Root    []byte `json:"root"`
+	Count   int    `json:"count"`
+}
+
+//UnmarshalJSON decodes a commitment of any version up to CommitmentVersion.
+func (c *Commitment) UnmarshalJSON(b []byte) error {
+	type commitment Commitment
+	return unmarshalVersioned(b, (*commitment)(c), func(v *commitment) *int { return &v.Version }, "commitment", CommitmentVersion)
+}
+
+//CommitReveal holds a list of items and their salts between the commit and the reveal.
+//Publishing its Commitment binds the committer to the contents and order of the list
+//without disclosing it.
+type CommitReveal struct {
+	items [][]byte
+	salts [][]byte
+	tree  *MerkleTree
+}
+
+//Reveal discloses a whole committed list.
+type Reveal struct {
+	Version int      `json:"version"`
+	Items   [][]byte `json:"items"`
+	Salts   [][]byte `json:"salts"`
+}
+
+//UnmarshalJSON decodes a reveal of any version up to RevealVersion.
+func (r *Reveal) UnmarshalJSON(b []byte) error {
+	type reveal Reveal
+	return unmarshalVersioned(b, (*reveal)(r), func(v *reveal) *int { return &v.Version }, "reveal", RevealVersion)
+}
+
+//ItemReveal discloses a single item of a committed list and its position.
+type ItemReveal struct {
+	Version  int      `json:"version"`
+	Position int      `json:"position"`
+	Item     []byte   `json:"item"`
+	Salt     []byte   `json:"salt"`
+	Path     [][]byte `json:"path"`
+	Index    []int64  `json:"index"`
+}
+
+//UnmarshalJSON decodes an item reveal of any version up to ItemRevealVersion.
+func (r *ItemReveal) UnmarshalJSON(b []byte) error {
+	type itemReveal ItemReveal
+	return unmarshalVersioned(b, (*itemReveal)(r), func(v *itemReveal) *int { return &v.Version }, "item reveal", ItemRevealVersion)
+}
+
+//NewCommitReveal commits to items, in order, with a fresh random salt per item.
+func NewCommitReveal(items [][]byte) (*CommitReveal, error) {
+	salts := make([][]byte, len(items))
+	for i := range salts {
+		salts[i] = make([]byte, saltSize)
+		if _, err := rand.Read(salts[i]); err != nil {
+			return nil, err
+		}
+	}
+	return newCommitReveal(items, salts)
+}
+
+//newCommitReveal builds the tree over items and salts.
+func newCommitReveal(items, salts [][]byte) (*CommitReveal, error) {
+	if len(items) != len(salts) {
+		return nil, fmt.Errorf("error: got %d salts for %d items", len(salts), len(items))
+	}
+	cs := make([]Content, 0, len(items))
+	for i := range items {
+		cs = append(cs, saltedItem{position: i, salt: salts[i], item: items[i]})
+	}
+	t, err := NewTree(cs, WithHasher(verify.SHA256))
+	if err != nil {
+		return nil, err
+	}
+	return &CommitReveal{items: items, salts: salts, tree: t}, nil
+}
+
+//Commitment returns the commitment to publish before the reveal.
+func (c *CommitReveal) Commitment() Commitment {
+	return Commitment{Version: CommitmentVersion, Root: c.tree.MerkleRoot(), Count: len(c.items)}
+}
+
+//Reveal returns the reveal of the whole list.
+func (c *CommitReveal) Reveal() *Reveal {
+	return &Reveal{Version: RevealVersion, Items: c.items, Salts: c.salts}
+}
+
+//RevealItem returns the reveal of the item at position i.
+func (c *CommitReveal) RevealItem(i int) (*ItemReveal, error) {
+	if i < 0 || i >= len(c.items) {
+		return nil, fmt.Errorf("error: position %d out of range", i)
+	}
+	path, index := c.tree.Leafs[i].merklePath()
+	return &ItemReveal{
+		Version:  ItemRevealVersion,
+		Position: i,
+		Item:     c.items[i],
+		Salt:     c.salts[i],
+		Path:     path,
+		Index:    index,
+	}, nil
+}
+
+//Verify checks that the revealed list is exactly the committed one, in the same order.
+func (r *Reveal) Verify(c Commitment) error {
+	if len(r.Items) != c.Count {
+		return fmt.Errorf("error: revealed %d items, commitment has %d", len(r.Items), c.Count)
+	}
+	cr, err := newCommitReveal(r.Items, r.Salts)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(cr.tree.MerkleRoot(), c.Root) {
+		return errors.New("error: reveal does not match commitment")
+	}
+	return nil
+}
+
+//Verify checks that the revealed item was committed at its position, in a list of the
+//committed length.
+func (r *ItemReveal) Verify(c Commitment) error {
+	if r.Position < 0 || r.Position >= c.Count {
+		return fmt.Errorf("error: position %d out of range", r.Position)
+	}
+	h, err := saltedItem{position: r.Position, salt: r.Salt, item: r.Item}.CalculateHash()
+	if err != nil {
+		return err
+	}
+	ok, err := verify.SHA256.VerifyPathAt(c.Root, h, r.Path, r.Index, r.Position, c.Count)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("error: reveal does not match commitment")
+	}
+	return nil
+}
+
+//Shuffle returns a permutation of the committed positions derived only from the
+//commitment and seed, so anyone can recompute it. The seed must not be known to the
+//committer before the commitment is published.
+func (c Commitment) Shuffle(seed []byte) []int {
+	perm := make([]int, c.Count)
+	for i := range perm {
+		perm[i] = i
+	}
+	r := newDetRand(c.Root, seed)
+	for i := len(perm) - 1; i > 0; i-- {
+		j := int(r.uniform(uint64(i + 1)))
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return perm
+}
+
+//detRand is a deterministic stream of random numbers: SHA-256 in counter mode over a
+//root and a seed.
+type detRand struct {
+	key     []byte
+	counter uint64
+	buf     []byte
+}
+
+func newDetRand(root, seed []byte) *detRand {
+	key := binary.AppendUvarint(nil, uint64(len(root)))
+	key = append(key, root...)
+	return &detRand{key: append(key, seed...)}
+}
+
+//uint64 returns the next 64 random bits.
+func (r *detRand) uint64() uint64 {
+	if len(r.buf) < 8 {
+		h := sha256.New()
+		h.Write(r.key)
+		h.Write(binary.BigEndian.AppendUint64(nil, r.counter))
+		r.counter++
+		r.buf = h.Sum(r.buf[:0])
+	}
+	v := binary.BigEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v
+}
+
+//uniform returns a number in [0, n) without modulo bias.
+func (r *detRand) uniform(n uint64) uint64 {
+	limit := -n % n // 2^64 mod n
+	for {
+		if v := r.uint64(); v >= limit {
+			return v % n
+		}
+	}
+}
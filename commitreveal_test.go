@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func testCommitReveal(t *testing.T, n int) *CommitReveal {
+	t.Helper()
+	var items [][]byte
+	for i := 0; i < n; i++ {
+		items = append(items, []byte(fmt.Sprint("ticket ", i)))
+	}
+	c, err := NewCommitReveal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRevealVerify(t *testing.T) {
+	c := testCommitReveal(t, 5)
+	com := c.Commitment()
+	r := c.Reveal()
+	if err := r.Verify(com); err != nil {
+		t.Fatal(err)
+	}
+	//the same items and salts in another order
+	swapped := &Reveal{Items: slices.Clone(r.Items), Salts: slices.Clone(r.Salts)}
+	swapped.Items[0], swapped.Items[1] = swapped.Items[1], swapped.Items[0]
+	swapped.Salts[0], swapped.Salts[1] = swapped.Salts[1], swapped.Salts[0]
+	if err := swapped.Verify(com); err == nil {
+		t.Error("reordered reveal verifies")
+	}
+	changed := &Reveal{Items: slices.Clone(r.Items), Salts: r.Salts}
+	changed.Items[2] = []byte("ticket 9")
+	if err := changed.Verify(com); err == nil {
+		t.Error("reveal with a changed item verifies")
+	}
+	short := &Reveal{Items: r.Items[:4], Salts: r.Salts[:4]}
+	if err := short.Verify(com); err == nil {
+		t.Error("reveal of fewer items verifies")
+	}
+}
+
+func TestItemRevealVerify(t *testing.T) {
+	c := testCommitReveal(t, 5)
+	com := c.Commitment()
+	for i := 0; i < 5; i++ {
+		r, err := c.RevealItem(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Verify(com); err != nil {
+			t.Fatalf("item %d: %v", i, err)
+		}
+		for j := -1; j <= 5; j++ {
+			if j == i {
+				continue
+			}
+			moved := *r
+			moved.Position = j
+			if err := moved.Verify(com); err == nil {
+				t.Errorf("item %d verifies at position %d", i, j)
+			}
+		}
+	}
+	if _, err := c.RevealItem(5); err == nil {
+		t.Error("reveal of position 5 of 5")
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	root := sha256.Sum256([]byte("root"))
+	c := Commitment{Root: root[:], Count: 10}
+	got := c.Shuffle([]byte("seed"))
+	//pinned so that a change to the derivation cannot go unnoticed
+	want := []int{3, 4, 9, 1, 2, 5, 0, 8, 6, 7}
+	if !slices.Equal(got, want) {
+		t.Errorf("Shuffle = %v, want %v", got, want)
+	}
+	if other := c.Shuffle([]byte("seed2")); slices.Equal(other, want) {
+		t.Error("another seed gives the same permutation")
+	}
+	if perm := (Commitment{Root: root[:], Count: 1000}).Shuffle([]byte("seed")); !isPermutation(perm) {
+		t.Error("Shuffle of 1000 items is not a permutation")
+	}
+	if perm := (Commitment{Root: root[:]}).Shuffle(nil); len(perm) != 0 {
+		t.Errorf("Shuffle of no items = %v", perm)
+	}
+}
+
+func isPermutation(perm []int) bool {
+	s := slices.Clone(perm)
+	slices.Sort(s)
+	for i, v := range s {
+		if v != i {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUniform(t *testing.T) {
+	for _, n := range []uint64{1, 2, 3, 7, 1<<63 + 1} {
+		a, b := newDetRand([]byte("root"), []byte("seed")), newDetRand([]byte("root"), []byte("seed"))
+		for i := 0; i < 100; i++ {
+			v := a.uniform(n)
+			if v >= n {
+				t.Fatalf("uniform(%d) = %d", n, v)
+			}
+			if w := b.uniform(n); w != v {
+				t.Fatalf("uniform(%d) is not deterministic: %d and %d", n, v, w)
+			}
+		}
+	}
+	r := newDetRand([]byte("root"), []byte("seed"))
+	var counts [3]int
+	for i := 0; i < 3000; i++ {
+		counts[r.uniform(3)]++
+	}
+	for v, c := range counts {
+		if c < 900 || c > 1100 {
+			t.Errorf("uniform(3) returned %d %d times out of 3000", v, c)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+//LeafMismatch describes a leaf whose hash differs between the local and a remote tree.
+type LeafMismatch struct {
+	Index      int
+	LocalHash  []byte
+	RemoteHash []byte
+	//HaveRaw is set when the raw content of both sides was available. Offset is then the
+	//first byte at which they differ, or -1 if the raw contents are identical and the
+	//difference comes from hashing or canonicalization.
+	HaveRaw   bool
+	Offset    int
+	LocalRaw  []byte
+	RemoteRaw []byte
+}
+
+//MismatchReport lists the leaves of a sampled range whose hashes differ between two parties.
+type MismatchReport struct {
+	Start      int
+	End        int
+	Mismatches []LeafMismatch
+}
+
+//leafCount returns the number of leaves without the duplicate added to even out the
+//last level.
+func (m *MerkleTree) leafCount() int {
+	n := len(m.Leafs)
+	if n > 0 && m.Leafs[n-1].dup {
+		n--
+	}
+	return n
+}
+
+//checkRange returns an error if [start, end) is not a range of leaves of the tree.
+func (m *MerkleTree) checkRange(start, end int) error {
+	if start < 0 || end < start || end > m.leafCount() {
+		return fmt.Errorf("error: leaf range [%d, %d) out of bounds", start, end)
+	}
+	return nil
+}
+
+//LeafHashes returns the hashes of the leaves in [start, end). This is what a party sends
+//to its peer when their roots disagree.
+func (m *MerkleTree) LeafHashes(start, end int) ([][]byte, error) {
+	if err := m.checkRange(start, end); err != nil {
+		return nil, err
+	}
+	hashes := make([][]byte, 0, end-start)
+	for _, l := range m.Leafs[start:end] {
+		hashes = append(hashes, l.Hash)
+	}
+	return hashes, nil
+}
+
+//LeafRaw returns the raw content of the leaves in [start, end). Entries are nil for
+//contents that do not implement RawContent.
+func (m *MerkleTree) LeafRaw(start, end int) ([][]byte, error) {
+	if err := m.checkRange(start, end); err != nil {
+		return nil, err
+	}
+	raws := make([][]byte, 0, end-start)
+	for _, l := range m.Leafs[start:end] {
+		var raw []byte
+		if rc, ok := l.C.(RawContent); ok {
+			var err error
+			if raw, err = rc.Raw(); err != nil {
+				return nil, err
+			}
+		}
+		raws = append(raws, raw)
+	}
+	return raws, nil
+}
+
+//DiagnoseMismatch compares the local leaves starting at start with the leaf hashes a
+//remote party returned from LeafHashes for the same range. remoteRaw is optional; when it
+//is given (as returned by the remote LeafRaw) the report contains the first byte at which
+//the raw contents of each differing leaf diverge.
+func (m *MerkleTree) DiagnoseMismatch(start int, remoteHashes, remoteRaw [][]byte) (*MismatchReport, error) {
+	if remoteRaw != nil && len(remoteRaw) != len(remoteHashes) {
+		return nil, fmt.Errorf("error: got %d remote raw contents for %d remote hashes", len(remoteRaw), len(remoteHashes))
+	}
+	end := start + len(remoteHashes)
+	localEnd := end
+	if n := m.leafCount(); localEnd > n {
+		localEnd = n
+	}
+	local, err := m.LeafHashes(start, localEnd)
+	if err != nil {
+		return nil, err
+	}
+	localRaw, err := m.LeafRaw(start, localEnd)
+	if err != nil {
+		return nil, err
+	}
+	r := &MismatchReport{Start: start, End: end}
+	for i, rh := range remoteHashes {
+		var lh, lraw, rraw []byte
+		if i < len(local) {
+			lh, lraw = local[i], localRaw[i]
+		}
+		if bytes.Equal(lh, rh) && lh != nil {
+			continue
+		}
+		if remoteRaw != nil {
+			rraw = remoteRaw[i]
+		}
+		mm := LeafMismatch{
+			Index:      start + i,
+			LocalHash:  lh,
+			RemoteHash: rh,
+			Offset:     -1,
+			LocalRaw:   lraw,
+			RemoteRaw:  rraw,
+		}
+		if lraw != nil && rraw != nil {
+			mm.HaveRaw = true
+			mm.Offset = firstDifference(lraw, rraw)
+		}
+		r.Mismatches = append(r.Mismatches, mm)
+	}
+	return r, nil
+}
+
+//firstDifference returns the index of the first byte at which a and b differ, or -1 if
+//they are equal.
+func firstDifference(a, b []byte) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) == len(b) {
+		return -1
+	}
+	if len(a) < len(b) {
+		return len(a)
+	}
+	return len(b)
+}
+
+//String returns a string representation of the report, one line per differing leaf.
+func (r *MismatchReport) String() string {
+	s := fmt.Sprintf("leaves [%d, %d): %d mismatches\n", r.Start, r.End, len(r.Mismatches))
+	for _, mm := range r.Mismatches {
+		s += fmt.Sprintf("leaf %d: local %x remote %x", mm.Index, mm.LocalHash, mm.RemoteHash)
+		switch {
+		case !mm.HaveRaw:
+		case mm.Offset < 0:
+			s += " raw content identical"
+		default:
+			s += fmt.Sprintf(" raw content differs at byte %d", mm.Offset)
+		}
+		s += "\n"
+	}
+	return s
+}
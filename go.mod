@@ -0,0 +1,3 @@
+module github.com/gaochaolyf/Go
+
+go 1.23
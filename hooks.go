@@ -0,0 +1,65 @@
+package main
+
+import "errors"
+
+//Hooks are optional callbacks invoked by a tree at key points of its operations, so
+//embedders can wire their own telemetry and alerting. Nil hooks are skipped. Hooks run
+//synchronously and should return quickly.
+type Hooks struct {
+	//OnLeafHashed is called with the index and hash of every leaf as the tree is built.
+	OnLeafHashed func(index int, hash []byte)
+	//OnLevelBuilt is called with the height and node count of every level above the
+	//leaves; the last call is for the root, with nodes equal to 1.
+	OnLevelBuilt func(level, nodes int)
+	//OnProofGenerated is called with the leaf hash and path length of every proof, once
+	//its walk through ProofSteps reaches the root.
+	OnProofGenerated func(leafHash []byte, depth int)
+	//OnVerifyFailed is called when MerkleTree.VerifyProof fails. Verifiers that only hold
+	//a root, such as Proof.Verify and the verify package, have no tree and cannot call it.
+	OnVerifyFailed func(leafHash []byte, err error)
+}
+
+//WithHooks returns an Option that installs h on the tree.
+func WithHooks(h Hooks) Option {
+	return func(t *MerkleTree) error {
+		t.hooks = h
+		return nil
+	}
+}
+
+func (h Hooks) leafHashed(index int, hash []byte) {
+	if h.OnLeafHashed != nil {
+		h.OnLeafHashed(index, hash)
+	}
+}
+
+func (h Hooks) levelBuilt(level, nodes int) {
+	if h.OnLevelBuilt != nil {
+		h.OnLevelBuilt(level, nodes)
+	}
+}
+
+func (h Hooks) proofGenerated(leafHash []byte, depth int) {
+	if h.OnProofGenerated != nil {
+		h.OnProofGenerated(leafHash, depth)
+	}
+}
+
+func (h Hooks) verifyFailed(leafHash []byte, err error) {
+	if h.OnVerifyFailed != nil {
+		h.OnVerifyFailed(leafHash, err)
+	}
+}
+
+//VerifyProof checks p for leafHash against the root of the tree, calling OnVerifyFailed
+//if it does not hold.
+func (m *MerkleTree) VerifyProof(leafHash []byte, p *Proof) (bool, error) {
+	ok, err := p.VerifyWith(m.hasher, m.MerkleRoot(), leafHash, m.leafCount())
+	switch {
+	case err != nil:
+		m.hooks.verifyFailed(leafHash, err)
+	case !ok:
+		m.hooks.verifyFailed(leafHash, errors.New("error: proof does not match root"))
+	}
+	return ok, err
+}
@@ -0,0 +1,75 @@
+package main
+
+import "iter"
+
+//ProofStep is one level of a Merkle path: the sibling hash and its index (1 if the
+//sibling is the right child, 0 if it is the left), as returned by GetMerklePath.
+type ProofStep struct {
+	Hash  []byte
+	Index int64
+}
+
+//All returns an iterator over every node of the tree in depth-first order, starting
+//at the root. Nodes shared as both children of a parent are yielded once.
+func (m *MerkleTree) All() iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		walkNodes(m.Root, yield)
+	}
+}
+
+//walkNodes yields n and its descendants and reports whether iteration should continue.
+func walkNodes(n *Node, yield func(*Node) bool) bool {
+	if n == nil {
+		return true
+	}
+	if !yield(n) || !walkNodes(n.Left, yield) {
+		return false
+	}
+	if n.Right == n.Left {
+		return true
+	}
+	return walkNodes(n.Right, yield)
+}
+
+//LeavesSeq returns an iterator over the leaf nodes in order. Unlike Leafs it skips the
+//duplicate appended to trees with an odd number of contents.
+func (m *MerkleTree) LeavesSeq() iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		for _, l := range m.Leafs {
+			if l.dup {
+				continue
+			}
+			if !yield(l) {
+				return
+			}
+		}
+	}
+}
+
+//ProofSteps returns an iterator over the Merkle path of leaf, from the leaf up to the root.
+//Indexes follow the position of the leaf, even where a sibling has the same hash. Every
+//proof of the tree is built from it; a walk that reaches the root calls OnProofGenerated.
+//A nil leaf has no steps.
+func (m *MerkleTree) ProofSteps(leaf *Node) iter.Seq[ProofStep] {
+	return func(yield func(ProofStep) bool) {
+		if leaf == nil {
+			return
+		}
+		current := leaf
+		currentParent := current.Parent
+		depth := 0
+		for currentParent != nil {
+			step := ProofStep{Hash: currentParent.Left.Hash, Index: 0} // left leaf
+			if currentParent.Left == current {
+				step = ProofStep{Hash: currentParent.Right.Hash, Index: 1} // right leaf
+			}
+			if !yield(step) {
+				return
+			}
+			depth++
+			current = currentParent
+			currentParent = currentParent.Parent
+		}
+		m.hooks.proofGenerated(leaf.Hash, depth)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func iterTree(t *testing.T, n int) *MerkleTree {
+	t.Helper()
+	var cs []Content
+	for i := 0; i < n; i++ {
+		cs = append(cs, TestContent{x: fmt.Sprint("item ", i)})
+	}
+	m, err := NewTree(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestAll(t *testing.T) {
+	//5 leaves and their duplicate, 3 nodes above them, then 2 and the root
+	m := iterTree(t, 5)
+	var nodes []*Node
+	for n := range m.All() {
+		nodes = append(nodes, n)
+	}
+	if len(nodes) != 12 {
+		t.Fatalf("got %d nodes, want 12", len(nodes))
+	}
+	if nodes[0] != m.Root {
+		t.Error("first node is not the root")
+	}
+	seen := make(map[*Node]bool)
+	for _, n := range nodes {
+		if seen[n] {
+			t.Fatalf("node %x yielded twice", n.Hash)
+		}
+		seen[n] = true
+	}
+	count := 0
+	for range m.All() {
+		if count++; count == 3 {
+			break
+		}
+	}
+	if count != 3 {
+		t.Errorf("break after 3 nodes, got %d", count)
+	}
+}
+
+func TestLeavesSeq(t *testing.T) {
+	for _, n := range []int{1, 4, 5} {
+		m := iterTree(t, n)
+		leaves := slices.Collect(m.LeavesSeq())
+		if len(leaves) != n {
+			t.Fatalf("%d contents: got %d leaves", n, len(leaves))
+		}
+		for i, l := range leaves {
+			if l != m.Leafs[i] || l.dup {
+				t.Errorf("%d contents: leaf %d is not Leafs[%d]", n, i, i)
+			}
+		}
+	}
+	count := 0
+	for range iterTree(t, 5).LeavesSeq() {
+		if count++; count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("break after 2 leaves, got %d", count)
+	}
+}
+
+func TestProofSteps(t *testing.T) {
+	m := iterTree(t, 5)
+	for i := 0; i < 5; i++ {
+		path, index, err := m.GetMerklePath(m.Leafs[i].C)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var steps []ProofStep
+		for s := range m.ProofSteps(m.Leafs[i]) {
+			steps = append(steps, s)
+		}
+		if len(steps) != len(path) {
+			t.Fatalf("leaf %d: got %d steps, want %d", i, len(steps), len(path))
+		}
+		for j, s := range steps {
+			if !slices.Equal(s.Hash, path[j]) || s.Index != index[j] {
+				t.Errorf("leaf %d: step %d differs from the merkle path", i, j)
+			}
+		}
+	}
+	//leaf 4 is paired with its duplicate, which has the same hash
+	if s := slices.Collect(m.ProofSteps(m.Leafs[4])); s[0].Index != 1 {
+		t.Error("leaf 4 is not the left child of its parent")
+	}
+	count := 0
+	for range m.ProofSteps(m.Leafs[0]) {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("break after 1 step, got %d", count)
+	}
+	for range m.ProofSteps(nil) {
+		t.Fatal("nil leaf has steps")
+	}
+}
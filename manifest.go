@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gaochaolyf/Go/verify"
+)
+
+//DefaultChunkSize is the chunk size used by CommitFile when none is given.
+const DefaultChunkSize = 4 << 20
+
+//ManifestVersion is the newest Manifest encoding this package reads and writes.
+//Manifests without a version predate versioning and are read as version 1.
+const ManifestVersion = 1
+
+//Kinds of files recorded in a Manifest.
+const (
+	KindShard   = "shard"
+	KindWeights = "weights"
+)
+
+//chunkHash is the hash of one chunk of a file. It is the Content of the leaves of a
+//file's chunk tree.
+type chunkHash []byte
+
+//CalculateHash returns the chunk hash itself
+func (c chunkHash) CalculateHash() ([]byte, error) {
+	return c, nil
+}
+
+//Equals tests for equality of two chunkHashes
+func (c chunkHash) Equals(other Content) (bool, error) {
+	o, ok := other.(chunkHash)
+	return ok && bytes.Equal(c, o), nil
+}
+
+//ManifestEntry commits to one dataset shard or model weight file: Root is the root of
+//a tree whose leaves are the hashes of the file's chunks.
+type ManifestEntry struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Size      int64  `json:"size"`
+	ChunkSize int    `json:"chunk_size"`
+	Root      []byte `json:"root"`
+}
+
+//bytes returns the encoding of the entry that is hashed into the manifest tree.
+func (e ManifestEntry) bytes() []byte {
+	b := []byte{'m'}
+	for _, s := range []string{e.Name, e.Kind} {
+		b = binary.AppendUvarint(b, uint64(len(s)))
+		b = append(b, s...)
+	}
+	b = binary.AppendVarint(b, e.Size)
+	b = binary.AppendVarint(b, int64(e.ChunkSize))
+	b = binary.AppendUvarint(b, uint64(len(e.Root)))
+	return append(b, e.Root...)
+}
+
+//CalculateHash hashes the encoding of a ManifestEntry
+func (e ManifestEntry) CalculateHash() ([]byte, error) {
+	return verify.SHA256.Leaf(e.bytes())
+}
+
+//Equals tests for equality of two ManifestEntries
+func (e ManifestEntry) Equals(other Content) (bool, error) {
+	o, ok := other.(ManifestEntry)
+	return ok && bytes.Equal(e.bytes(), o.bytes()), nil
+}
+
+//Manifest is the commitment to the dataset shards and model weights of a training run.
+//Entries are sorted by name and Root is the root of a tree over them, so recording Root
+//with a run pins exactly which data and weights it used. Manifest and chunk trees are
+//hashed with verify.SHA256.
+type Manifest struct {
+	Version int             `json:"version"`
+	Entries []ManifestEntry `json:"entries"`
+	Root    []byte          `json:"root"`
+	tree    *MerkleTree
+}
+
+//CommitFile reads r in chunks of chunkSize bytes and returns the manifest entry that
+//commits to its content. A chunkSize of 0 selects DefaultChunkSize.
+func CommitFile(name, kind string, r io.Reader, chunkSize int) (ManifestEntry, error) {
+	e, _, err := CommitFileChunks(name, kind, r, chunkSize)
+	return e, err
+}
+
+//CommitFileChunks is like CommitFile but also returns the chunk hashes of the file. They
+//are published next to the file so readers can verify it chunk by chunk with
+//NewVerifiedReader.
+func CommitFileChunks(name, kind string, r io.Reader, chunkSize int) (ManifestEntry, [][]byte, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < 0 {
+		return ManifestEntry{}, nil, fmt.Errorf("error: invalid chunk size %d", chunkSize)
+	}
+	t, size, err := chunkTree(r, chunkSize)
+	if err != nil {
+		return ManifestEntry{}, nil, err
+	}
+	chunks, err := t.LeafHashes(0, t.leafCount())
+	if err != nil {
+		return ManifestEntry{}, nil, err
+	}
+	return ManifestEntry{
+		Name:      name,
+		Kind:      kind,
+		Size:      size,
+		ChunkSize: chunkSize,
+		Root:      t.MerkleRoot(),
+	}, chunks, nil
+}
+
+//chunkTree builds the chunk tree of r and returns it with the number of bytes read. An
+//empty file has a single empty chunk.
+func chunkTree(r io.Reader, chunkSize int) (*MerkleTree, int64, error) {
+	var cs []Content
+	var size int64
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 || (len(cs) == 0 && err == io.EOF) {
+			h, err := hashChunk(buf[:n])
+			if err != nil {
+				return nil, 0, err
+			}
+			cs = append(cs, h)
+			size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	t, err := NewTree(cs, WithHasher(verify.SHA256))
+	if err != nil {
+		return nil, 0, err
+	}
+	return t, size, nil
+}
+
+//hashChunk returns the leaf hash of a chunk.
+func hashChunk(b []byte) (chunkHash, error) {
+	return verify.SHA256.Leaf(b)
+}
+
+//VerifyFile reads r and returns an error unless its content matches e.
+func VerifyFile(e ManifestEntry, r io.Reader) error {
+	if e.ChunkSize <= 0 {
+		return fmt.Errorf("error: %s: invalid chunk size %d", e.Name, e.ChunkSize)
+	}
+	t, size, err := chunkTree(r, e.ChunkSize)
+	if err != nil {
+		return err
+	}
+	if size != e.Size {
+		return fmt.Errorf("error: %s: size is %d, manifest has %d", e.Name, size, e.Size)
+	}
+	if !bytes.Equal(t.MerkleRoot(), e.Root) {
+		return fmt.Errorf("error: %s: content does not match manifest root", e.Name)
+	}
+	return nil
+}
+
+//NewManifest creates a Manifest from entries. Entry names must be unique.
+func NewManifest(entries []ManifestEntry) (*Manifest, error) {
+	m := &Manifest{Version: ManifestVersion, Entries: append([]ManifestEntry(nil), entries...)}
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Name < m.Entries[j].Name })
+	if err := m.build(); err != nil {
+		return nil, err
+	}
+	m.Root = m.tree.MerkleRoot()
+	return m, nil
+}
+
+//UnmarshalJSON decodes a manifest of any version up to ManifestVersion.
+func (m *Manifest) UnmarshalJSON(b []byte) error {
+	type manifest Manifest
+	return unmarshalVersioned(b, (*manifest)(m), func(v *manifest) *int { return &v.Version }, "manifest", ManifestVersion)
+}
+
+//build builds the tree over the sorted entries.
+func (m *Manifest) build() error {
+	cs := make([]Content, 0, len(m.Entries))
+	for i, e := range m.Entries {
+		if i > 0 && m.Entries[i-1].Name >= e.Name {
+			return fmt.Errorf("error: manifest entries not sorted or duplicate name %q", e.Name)
+		}
+		cs = append(cs, e)
+	}
+	t, err := NewTree(cs, WithHasher(verify.SHA256))
+	if err != nil {
+		return err
+	}
+	m.tree = t
+	return nil
+}
+
+//Verify checks that Root commits to Entries, for instance after decoding a manifest
+//recorded with a training run.
+func (m *Manifest) Verify() error {
+	if err := m.build(); err != nil {
+		return err
+	}
+	if !bytes.Equal(m.tree.MerkleRoot(), m.Root) {
+		return errors.New("error: manifest entries do not match manifest root")
+	}
+	return nil
+}
+
+//Entry returns the entry named name.
+func (m *Manifest) Entry(name string) (ManifestEntry, bool) {
+	i := sort.Search(len(m.Entries), func(i int) bool { return m.Entries[i].Name >= name })
+	if i < len(m.Entries) && m.Entries[i].Name == name {
+		return m.Entries[i], true
+	}
+	return ManifestEntry{}, false
+}
+
+//ProveEntry returns the Merkle path of the entry named name in the manifest tree, so a
+//reader that only trusts the manifest root can check the entry with VerifyEntry.
+func (m *Manifest) ProveEntry(name string) ([][]byte, []int64, error) {
+	i := sort.Search(len(m.Entries), func(i int) bool { return m.Entries[i].Name >= name })
+	if i == len(m.Entries) || m.Entries[i].Name != name {
+		return nil, nil, fmt.Errorf("error: no manifest entry %q", name)
+	}
+	if m.tree == nil {
+		if err := m.build(); err != nil {
+			return nil, nil, err
+		}
+	}
+	path, index := m.tree.Leafs[i].merklePath()
+	return path, index, nil
+}
+
+//VerifyEntry checks that e is part of the manifest with the given root.
+func VerifyEntry(root []byte, e ManifestEntry, path [][]byte, index []int64) error {
+	h, err := e.CalculateHash()
+	if err != nil {
+		return err
+	}
+	ok, err := verify.SHA256.VerifyPath(root, h, path, index)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("error: %s: entry is not part of manifest root", e.Name)
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gaochaolyf/Go/verify"
+)
+
+//Kinds of changes between two manifests.
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+//ShardChange is one entry that differs between two manifests. From is the entry in the
+//older manifest and To the entry in the newer one; each is set with its Merkle path when
+//it exists on that side. On the side where the entry does not exist, FromAbsent or
+//ToAbsent proves that it does not.
+type ShardChange struct {
+	Name       string         `json:"name"`
+	Change     string         `json:"change"`
+	From       *ManifestEntry `json:"from,omitempty"`
+	FromPath   [][]byte       `json:"from_path,omitempty"`
+	FromIndex  []int64        `json:"from_index,omitempty"`
+	FromAbsent *AbsenceProof  `json:"from_absent,omitempty"`
+	To         *ManifestEntry `json:"to,omitempty"`
+	ToPath     [][]byte       `json:"to_path,omitempty"`
+	ToIndex    []int64        `json:"to_index,omitempty"`
+	ToAbsent   *AbsenceProof  `json:"to_absent,omitempty"`
+}
+
+//EntryProof is a manifest entry with its position in the manifest tree and its path.
+type EntryProof struct {
+	Entry ManifestEntry `json:"entry"`
+	Leaf  int           `json:"leaf"`
+	Path  [][]byte      `json:"path"`
+	Index []int64       `json:"index"`
+}
+
+//AbsenceProof proves that a manifest of Size entries has no entry of a given name.
+//Before and After are the entries on either side of the name, which must be adjacent in
+//the tree; Before is nil if the name sorts before the first entry and After if it sorts
+//after the last one.
+type AbsenceProof struct {
+	Size   int         `json:"size"`
+	Before *EntryProof `json:"before,omitempty"`
+	After  *EntryProof `json:"after,omitempty"`
+}
+
+//ManifestDiff is an audit report of the entries that changed between two manifest roots.
+//Every change carries inclusion and absence proofs, so anyone who only knows the two
+//roots can check with Verify that each listed change happened. That alone does not show
+//that no change is missing from the report: VerifyComplete does, given either manifest.
+type ManifestDiff struct {
+	Version  int           `json:"version"`
+	FromRoot []byte        `json:"from_root"`
+	ToRoot   []byte        `json:"to_root"`
+	Changes  []ShardChange `json:"changes"`
+}
+
+//UnmarshalJSON decodes a report of any version up to ManifestDiffVersion.
+func (d *ManifestDiff) UnmarshalJSON(b []byte) error {
+	type manifestDiff ManifestDiff
+	return unmarshalVersioned(b, (*manifestDiff)(d), func(v *manifestDiff) *int { return &v.Version }, "manifest diff", ManifestDiffVersion)
+}
+
+//DiffManifests returns the shards added, removed and modified between from and to.
+//Both manifests are checked against their roots first. Shards are compared by their
+//entries, so no shard content is rehashed. Producing the report needs both manifests;
+//auditing it needs only the roots and, to check completeness, one of them.
+func DiffManifests(from, to *Manifest) (*ManifestDiff, error) {
+	if err := from.Verify(); err != nil {
+		return nil, err
+	}
+	if err := to.Verify(); err != nil {
+		return nil, err
+	}
+	d := &ManifestDiff{Version: ManifestDiffVersion, FromRoot: from.Root, ToRoot: to.Root}
+	if bytes.Equal(from.Root, to.Root) {
+		return d, nil
+	}
+	i, j := 0, 0
+	for i < len(from.Entries) || j < len(to.Entries) {
+		var c ShardChange
+		switch {
+		case j == len(to.Entries) || (i < len(from.Entries) && from.Entries[i].Name < to.Entries[j].Name):
+			c = ShardChange{Name: from.Entries[i].Name, Change: ChangeRemoved}
+			c.From, c.FromPath, c.FromIndex = from.entryAt(i)
+			c.ToAbsent = to.proveAbsence(c.Name)
+			i++
+		case i == len(from.Entries) || from.Entries[i].Name > to.Entries[j].Name:
+			c = ShardChange{Name: to.Entries[j].Name, Change: ChangeAdded}
+			c.To, c.ToPath, c.ToIndex = to.entryAt(j)
+			c.FromAbsent = from.proveAbsence(c.Name)
+			j++
+		default:
+			same, err := from.Entries[i].Equals(to.Entries[j])
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				i++
+				j++
+				continue
+			}
+			c = ShardChange{Name: to.Entries[j].Name, Change: ChangeModified}
+			c.From, c.FromPath, c.FromIndex = from.entryAt(i)
+			c.To, c.ToPath, c.ToIndex = to.entryAt(j)
+			i++
+			j++
+		}
+		d.Changes = append(d.Changes, c)
+	}
+	return d, nil
+}
+
+//entryAt returns a copy of entry i and its Merkle path. The tree must have been built.
+func (m *Manifest) entryAt(i int) (*ManifestEntry, [][]byte, []int64) {
+	e := m.Entries[i]
+	path, index := m.tree.Leafs[i].merklePath()
+	return &e, path, index
+}
+
+//proveAbsence returns the proof that m has no entry named name. The tree must have been
+//built.
+func (m *Manifest) proveAbsence(name string) *AbsenceProof {
+	i := sort.Search(len(m.Entries), func(i int) bool { return m.Entries[i].Name >= name })
+	p := &AbsenceProof{Size: len(m.Entries)}
+	if i > 0 {
+		p.Before = m.entryProof(i - 1)
+	}
+	if i < len(m.Entries) {
+		p.After = m.entryProof(i)
+	}
+	return p
+}
+
+//entryProof returns the EntryProof of entry i. The tree must have been built.
+func (m *Manifest) entryProof(i int) *EntryProof {
+	path, index := m.tree.Leafs[i].merklePath()
+	return &EntryProof{Entry: m.Entries[i], Leaf: i, Path: path, Index: index}
+}
+
+//verify checks that the entry is at its position in a manifest of size entries with the
+//given root.
+func (p *EntryProof) verify(root []byte, size int) error {
+	h, err := p.Entry.CalculateHash()
+	if err != nil {
+		return err
+	}
+	ok, err := verify.SHA256.VerifyPathAt(root, h, p.Path, p.Index, p.Leaf, size)
+	if err != nil {
+		return fmt.Errorf("error: %s: %v", p.Entry.Name, err)
+	}
+	if !ok {
+		return fmt.Errorf("error: %s: entry is not part of manifest root", p.Entry.Name)
+	}
+	return nil
+}
+
+//Verify checks that the manifest with the given root has no entry named name. Entry
+//names are unique, so the position checks of the neighbours also pin Size: the last
+//entry of a smaller manifest would have a sibling in the real tree.
+func (p *AbsenceProof) Verify(root []byte, name string) error {
+	if p.Before == nil && p.After == nil {
+		return fmt.Errorf("error: %s: absence proof has no neighbours", name)
+	}
+	if p.Before != nil {
+		if p.Before.Entry.Name >= name {
+			return fmt.Errorf("error: %s: entry %q does not sort before it", name, p.Before.Entry.Name)
+		}
+		if p.After == nil && p.Before.Leaf != p.Size-1 {
+			return fmt.Errorf("error: %s: entry %q is not the last one", name, p.Before.Entry.Name)
+		}
+		if err := p.Before.verify(root, p.Size); err != nil {
+			return err
+		}
+	}
+	if p.After != nil {
+		if p.After.Entry.Name <= name {
+			return fmt.Errorf("error: %s: entry %q does not sort after it", name, p.After.Entry.Name)
+		}
+		if p.Before == nil && p.After.Leaf != 0 {
+			return fmt.Errorf("error: %s: entry %q is not the first one", name, p.After.Entry.Name)
+		}
+		if p.Before != nil && p.After.Leaf != p.Before.Leaf+1 {
+			return fmt.Errorf("error: %s: entries %q and %q are not adjacent", name, p.Before.Entry.Name, p.After.Entry.Name)
+		}
+		if err := p.After.verify(root, p.Size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Verify checks every change in the report against FromRoot and ToRoot: entries that are
+//present are part of their root and entries that are absent are proven absent. Changes
+//must be sorted by name, and a report between different roots cannot be empty.
+func (d *ManifestDiff) Verify() error {
+	same := bytes.Equal(d.FromRoot, d.ToRoot)
+	if same && len(d.Changes) > 0 {
+		return errors.New("error: report lists changes between equal roots")
+	}
+	if !same && len(d.Changes) == 0 {
+		return errors.New("error: roots differ but report lists no changes")
+	}
+	for i, c := range d.Changes {
+		if i > 0 && d.Changes[i-1].Name >= c.Name {
+			return fmt.Errorf("error: %s: changes not sorted or duplicate name", c.Name)
+		}
+		wantFrom := c.Change == ChangeRemoved || c.Change == ChangeModified
+		wantTo := c.Change == ChangeAdded || c.Change == ChangeModified
+		if !wantFrom && !wantTo {
+			return fmt.Errorf("error: %s: unknown change %q", c.Name, c.Change)
+		}
+		if wantFrom != (c.From != nil) || wantTo != (c.To != nil) ||
+			wantFrom == (c.FromAbsent != nil) || wantTo == (c.ToAbsent != nil) {
+			return fmt.Errorf("error: %s: %s change has the wrong entries", c.Name, c.Change)
+		}
+		if c.Change == ChangeModified {
+			if unchanged, err := c.From.Equals(*c.To); err != nil || unchanged {
+				return fmt.Errorf("error: %s: modified entry is unchanged", c.Name)
+			}
+		}
+		if c.From != nil {
+			if c.From.Name != c.Name {
+				return fmt.Errorf("error: %s: entry is named %q", c.Name, c.From.Name)
+			}
+			if err := VerifyEntry(d.FromRoot, *c.From, c.FromPath, c.FromIndex); err != nil {
+				return err
+			}
+		}
+		if c.To != nil {
+			if c.To.Name != c.Name {
+				return fmt.Errorf("error: %s: entry is named %q", c.Name, c.To.Name)
+			}
+			if err := VerifyEntry(d.ToRoot, *c.To, c.ToPath, c.ToIndex); err != nil {
+				return err
+			}
+		}
+		if c.FromAbsent != nil {
+			if err := c.FromAbsent.Verify(d.FromRoot, c.Name); err != nil {
+				return err
+			}
+		}
+		if c.ToAbsent != nil {
+			if err := c.ToAbsent.Verify(d.ToRoot, c.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//VerifyComplete checks the report with Verify and then that it lists every change: m is
+//the manifest at either root, and applying the changes to its entries, forwards from
+//FromRoot or backwards from ToRoot, must produce the manifest at the other root.
+func (d *ManifestDiff) VerifyComplete(m *Manifest) error {
+	if err := d.Verify(); err != nil {
+		return err
+	}
+	if err := m.Verify(); err != nil {
+		return err
+	}
+	var forward bool
+	var want []byte
+	switch {
+	case bytes.Equal(m.Root, d.FromRoot):
+		forward, want = true, d.ToRoot
+	case bytes.Equal(m.Root, d.ToRoot):
+		forward, want = false, d.FromRoot
+	default:
+		return errors.New("error: manifest is at neither root of the report")
+	}
+	entries := make(map[string]ManifestEntry, len(m.Entries))
+	for _, e := range m.Entries {
+		entries[e.Name] = e
+	}
+	for _, c := range d.Changes {
+		old, next := c.From, c.To
+		if !forward {
+			old, next = next, old
+		}
+		e, ok := entries[c.Name]
+		if ok != (old != nil) || (ok && !bytes.Equal(e.bytes(), old.bytes())) {
+			return fmt.Errorf("error: %s: change does not apply to the manifest", c.Name)
+		}
+		delete(entries, c.Name)
+		if next != nil {
+			entries[c.Name] = *next
+		}
+	}
+	list := make([]ManifestEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	other, err := NewManifest(list)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(other.Root, want) {
+		return errors.New("error: report does not list every change between the roots")
+	}
+	return nil
+}
+
+//String returns a string representation of the report, one line per change.
+func (d *ManifestDiff) String() string {
+	s := fmt.Sprintf("%x -> %x: %d changes\n", d.FromRoot, d.ToRoot, len(d.Changes))
+	for _, c := range d.Changes {
+		s += fmt.Sprintf("%s %s\n", c.Change, c.Name)
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testManifest(t *testing.T, names ...string) *Manifest {
+	t.Helper()
+	var entries []ManifestEntry
+	for _, n := range names {
+		entries = append(entries, ManifestEntry{Name: n, Kind: KindShard, Size: 1, ChunkSize: 1, Root: []byte(n)})
+	}
+	m, err := NewManifest(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDiffManifests(t *testing.T) {
+	from := testManifest(t, "b", "c", "d", "f", "h")
+	to := testManifest(t, "a", "c", "e", "f", "h", "i")
+	to.Entries[1].Size = 2 // modify c
+	if err := to.build(); err != nil {
+		t.Fatal(err)
+	}
+	to.Root = to.tree.MerkleRoot()
+	d, err := DiffManifests(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "added a,removed b,modified c,removed d,added e,added i,"
+	got := ""
+	for _, c := range d.Changes {
+		got += fmt.Sprintf("%s %s,", c.Change, c.Name)
+	}
+	if got != want {
+		t.Fatalf("changes = %q, want %q", got, want)
+	}
+	if err := d.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*Manifest{from, to} {
+		if err := d.VerifyComplete(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//dropping a change keeps the remaining ones valid but not complete
+	partial := *d
+	partial.Changes = d.Changes[1:]
+	if err := partial.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := partial.VerifyComplete(from); err == nil {
+		t.Error("incomplete report verifies as complete")
+	}
+
+	empty := *d
+	empty.Changes = nil
+	if err := empty.Verify(); err == nil {
+		t.Error("empty report between different roots verifies")
+	}
+}
+
+func TestDiffManifestsForgedAbsence(t *testing.T) {
+	from := testManifest(t, "a", "b", "c", "d", "e")
+	to := testManifest(t, "a", "b", "c", "d", "e", "f")
+	d, err := DiffManifests(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	//claim that c was added: c exists in from, between b and d
+	c := ShardChange{Name: "c", Change: ChangeAdded, FromAbsent: from.proveAbsence("c")}
+	c.To, c.ToPath, c.ToIndex = to.entryAt(2)
+	c.FromAbsent.After = from.entryProof(3)
+	forged := &ManifestDiff{FromRoot: d.FromRoot, ToRoot: d.ToRoot, Changes: []ShardChange{c, d.Changes[0]}}
+	if err := forged.Verify(); err == nil {
+		t.Error("absence proof with non-adjacent neighbours verifies")
+	}
+	//claim that e is the last entry of to, so that nothing sorts after it: to has six
+	//entries, and a tree of five entries has the same depth
+	p := &AbsenceProof{Size: 5, Before: to.entryProof(4)}
+	if err := p.Verify(to.Root, "ee"); err == nil {
+		t.Error("entry e proven to be the last of five entries")
+	}
+	p.Size = 6
+	if err := p.Verify(to.Root, "ee"); err == nil {
+		t.Error("entry e proven to be the last of six entries")
+	}
+}
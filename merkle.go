@@ -1,12 +1,15 @@
 package main
 
 import (
-	"bytes"
 	"crypto/md5"
 	"errors"
+	"flag"
 	"fmt"
 	"hash"
 	"log"
+	"time"
+
+	"github.com/gaochaolyf/Go/verify"
 )
 
 //Content represents the data that is stored and verified by the tree. A type that
@@ -19,12 +22,19 @@ type Content interface {
 //MerkleTree is the container for the tree. It holds a pointer to the root of the tree,
 //a list of pointers to the leaf nodes, and the merkle root.
 type MerkleTree struct {
-	Root         *Node
-	merkleRoot   []byte
-	Leafs        []*Node
-	hashStrategy func() hash.Hash
+	Root          *Node
+	merkleRoot    []byte
+	Leafs         []*Node
+	hasher        verify.Hasher
+	canonicalizer Canonicalizer
+	proofOrder    ProofOrder
+	hooks         Hooks
 }
 
+//Option configures a MerkleTree created by NewTree. An Option that returns an error
+//makes NewTree fail with it.
+type Option func(*MerkleTree) error
+
 //Node represents a node, root, or leaf in the tree. It stores pointers to its immediate
 //relationships, a hash, the content stored if it is a leaf, and other metadata.
 type Node struct {
@@ -38,12 +48,43 @@ type Node struct {
 	C      Content
 }
 
+//defaultHashStrategy is the hash used to combine child hashes into intermediate nodes.
+//var defaultHashStrategy = sha256.New
+var defaultHashStrategy = md5.New
+
+//WithHashStrategy returns an Option that sets the hash used to combine child hashes
+//and, with WithCanonicalizer, to hash leaves. The default is MD5.
+func WithHashStrategy(hashStrategy func() hash.Hash) Option {
+	return WithHasher(verify.Hasher{New: hashStrategy})
+}
+
+//WithHasher is like WithHashStrategy but also sets the prefixes that separate leaf from
+//node hashes, for instance to verify.SHA256. Contents that compute their own hash must
+//hash like h.Leaf.
+func WithHasher(h verify.Hasher) Option {
+	return func(t *MerkleTree) error {
+		if h.New == nil {
+			return errors.New("error: no hash strategy")
+		}
+		t.hasher = h
+		return nil
+	}
+}
+
+//Hasher returns how the tree hashes its leaves and nodes, to verify its paths with.
+func (m *MerkleTree) Hasher() verify.Hasher {
+	return m.hasher
+}
+
 //NewTree creates a new Merkle Tree using the content cs.
-func NewTree(cs []Content) (*MerkleTree, error) {
-	//var defaultHashStrategy = sha256.New
-	var defaultHashStrategy = md5.New
+func NewTree(cs []Content, opts ...Option) (*MerkleTree, error) {
 	t := &MerkleTree{
-		hashStrategy: defaultHashStrategy,
+		hasher: verify.Hasher{New: defaultHashStrategy},
+	}
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return nil, err
+		}
 	}
 	root, leafs, err := buildWithContent(cs, t)
 	if err != nil {
@@ -55,7 +96,7 @@ func NewTree(cs []Content) (*MerkleTree, error) {
 	return t, nil
 }
 
-// GetMerklePath: Get Merkle path and indexes(left leaf or right leaf)
+// GetMerklePath: Get Merkle path and indexes(left leaf or right leaf), ordered from the leaf up to the root
 func (m *MerkleTree) GetMerklePath(content Content) ([][]byte, []int64, error) {
 	for _, current := range m.Leafs {
 		ok, err := current.C.Equals(content)
@@ -64,26 +105,25 @@ func (m *MerkleTree) GetMerklePath(content Content) ([][]byte, []int64, error) {
 		}
 
 		if ok {
-			currentParent := current.Parent
-			var merklePath [][]byte
-			var index []int64
-			for currentParent != nil {
-				if bytes.Equal(currentParent.Left.Hash, current.Hash) {
-					merklePath = append(merklePath, currentParent.Right.Hash)
-					index = append(index, 1) // right leaf
-				} else {
-					merklePath = append(merklePath, currentParent.Left.Hash)
-					index = append(index, 0) // left leaf
-				}
-				current = currentParent
-				currentParent = currentParent.Parent
-			}
+			merklePath, index := current.merklePath()
 			return merklePath, index, nil
 		}
 	}
 	return nil, nil, nil
 }
 
+//merklePath walks from n up to the root and returns the sibling hashes along the way
+//together with their indexes (1 if the sibling is the right child, 0 if it is the left).
+func (n *Node) merklePath() ([][]byte, []int64) {
+	var merklePath [][]byte
+	var index []int64
+	for step := range n.Tree.ProofSteps(n) {
+		merklePath = append(merklePath, step.Hash)
+		index = append(index, step.Index)
+	}
+	return merklePath, index
+}
+
 //buildWithContent is a helper function that for a given set of Contents, generates a
 //corresponding tree and returns the root node, a list of leaf nodes, and a possible error.
 //Returns an error if cs contains no Contents.
@@ -92,11 +132,12 @@ func buildWithContent(cs []Content, t *MerkleTree) (*Node, []*Node, error) {
 		return nil, nil, errors.New("error: cannot construct tree with no content")
 	}
 	var leafs []*Node
-	for _, c := range cs {
-		hash, err := c.CalculateHash()
+	for i, c := range cs {
+		hash, err := t.HashContent(c)
 		if err != nil {
 			return nil, nil, err
 		}
+		t.hooks.leafHashed(i, hash)
 
 		leafs = append(leafs, &Node{
 			Hash: hash,
@@ -115,7 +156,7 @@ func buildWithContent(cs []Content, t *MerkleTree) (*Node, []*Node, error) {
 		}
 		leafs = append(leafs, duplicate)
 	}
-	root, err := buildIntermediate(leafs, t)
+	root, err := buildIntermediate(leafs, t, 1)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -124,33 +165,35 @@ func buildWithContent(cs []Content, t *MerkleTree) (*Node, []*Node, error) {
 }
 
 //buildIntermediate is a helper function that for a given list of leaf nodes, constructs
-//the intermediate and root levels of the tree. Returns the resulting root node of the tree.
-func buildIntermediate(nl []*Node, t *MerkleTree) (*Node, error) {
+//the intermediate and root levels of the tree, level being the height of the level built
+//by this call. Returns the resulting root node of the tree.
+func buildIntermediate(nl []*Node, t *MerkleTree, level int) (*Node, error) {
 	var nodes []*Node
 	for i := 0; i < len(nl); i += 2 {
-		h := t.hashStrategy()
 		var left, right int = i, i + 1
 		if i+1 == len(nl) {
 			right = i
 		}
-		chash := append(nl[left].Hash, nl[right].Hash...)
-		if _, err := h.Write(chash); err != nil {
+		hash, err := t.hasher.Node(nl[left].Hash, nl[right].Hash)
+		if err != nil {
 			return nil, err
 		}
 		n := &Node{
 			Left:  nl[left],
 			Right: nl[right],
-			Hash:  h.Sum(nil),
+			Hash:  hash,
 			Tree:  t,
 		}
 		nodes = append(nodes, n)
 		nl[left].Parent = n
 		nl[right].Parent = n
 		if len(nl) == 2 {
+			t.hooks.levelBuilt(level, 1)
 			return n, nil
 		}
 	}
-	return buildIntermediate(nodes, t)
+	t.hooks.levelBuilt(level, len(nodes))
+	return buildIntermediate(nodes, t, level+1)
 }
 
 //MerkleRoot returns the unverified Merkle Root (hash of the root node) of the tree.
@@ -194,6 +237,30 @@ func (t TestContent) Equals(other Content) (bool, error) {
 }
 
 func main() {
+	soakFor := flag.Duration("soak", 0, "run a soak test for this long instead of the example")
+	soakSeed := flag.Int64("soak-seed", 1, "seed of the soak workload")
+	soakMix := flag.String("soak-mix", "append=1,update=1,proof=4,verify=4", "weights of the soak operations")
+	soakMaxLeaves := flag.Int("soak-max-leaves", 4096, "leaf count after which soak appends become updates")
+	soakReport := flag.Duration("soak-report", time.Minute, "interval between soak progress reports")
+	flag.Parse()
+	if *soakFor > 0 {
+		mix, err := parseSoakMix(*soakMix)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = runSoak(soakConfig{
+			Duration:  *soakFor,
+			Seed:      *soakSeed,
+			Mix:       mix,
+			MaxLeaves: *soakMaxLeaves,
+			Report:    *soakReport,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	//Build list of Content to build tree
 	var list []Content
 	list = append(list, TestContent{x: "Hello"})
@@ -0,0 +1,80 @@
+//Package mobile is the verification API for mobile apps. Its functions only take and
+//return byte slices, strings, ints and bools so that the package can be exported with
+//gomobile bind, and they verify against a pinned root without any network access.
+package mobile
+
+import "github.com/gaochaolyf/Go/verify"
+
+//Names of the hashers a tree can be built with, passed as the hasher argument: MD5 for
+//trees built with the default hash strategy and SHA256 for the trees hashed with
+//verify.SHA256, such as manifests, revocation lists and commit-reveal commitments.
+const (
+	MD5    = "md5"
+	SHA256 = "sha256"
+)
+
+//lookup returns the verify.Hasher named name.
+func lookup(name string) (verify.Hasher, bool) {
+	switch name {
+	case MD5:
+		return verify.MD5, true
+	case SHA256:
+		return verify.SHA256, true
+	}
+	return verify.Hasher{}, false
+}
+
+//VerifyPath reports whether leafHash is the leaf at position leaf of the tree of size
+//leaves with the given root, hashed with the named hasher. path is the concatenation of
+//the sibling hashes from the leaf up, each as long as leafHash, and index holds one byte
+//per level: 1 if the sibling is the right child, 0 if it is the left one. The size must
+//be pinned with the root.
+func VerifyPath(hasher string, root, leafHash, path, index []byte, leaf, size int) bool {
+	h, ok := lookup(hasher)
+	hashSize := len(leafHash)
+	if !ok || hashSize == 0 || len(path) != len(index)*hashSize {
+		return false
+	}
+	siblings := make([][]byte, len(index))
+	idx := make([]int64, len(index))
+	for i := range index {
+		siblings[i] = path[i*hashSize : (i+1)*hashSize]
+		idx[i] = int64(index[i])
+	}
+	ok, err := h.VerifyPathAt(root, leafHash, siblings, idx, leaf, size)
+	return err == nil && ok
+}
+
+//VerifyData is like VerifyPath but takes the leaf content and hashes it first.
+func VerifyData(hasher string, root, data, path, index []byte, leaf, size int) bool {
+	h, ok := lookup(hasher)
+	if !ok {
+		return false
+	}
+	leafHash, err := h.Leaf(data)
+	return err == nil && VerifyPath(hasher, root, leafHash, path, index, leaf, size)
+}
+
+//VerifyBundle reports whether the base45 proof bundle, as scanned from a QR code, is
+//valid for the given root of a tree of size leaves hashed with the named hasher.
+func VerifyBundle(hasher string, root []byte, size int, bundle string) bool {
+	h, ok := lookup(hasher)
+	if !ok {
+		return false
+	}
+	b, err := verify.DecodeProofBundle(bundle)
+	if err != nil {
+		return false
+	}
+	return b.Verify(h, root, size) == nil
+}
+
+//BundleData returns the leaf content of a base45 proof bundle, or nil if it cannot be
+//decoded, so an app can display what a verified bundle proves.
+func BundleData(bundle string) []byte {
+	b, err := verify.DecodeProofBundle(bundle)
+	if err != nil {
+		return nil
+	}
+	return b.Data
+}
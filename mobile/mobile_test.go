@@ -0,0 +1,46 @@
+package mobile
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVerifyData(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	for _, name := range []string{MD5, SHA256} {
+		h, _ := lookup(name)
+		var leaves [][]byte
+		for _, d := range data {
+			l, err := h.Leaf(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaves = append(leaves, l)
+		}
+		//c is the last leaf of an odd level and is paired with itself
+		ab, _ := h.Node(leaves[0], leaves[1])
+		cc, _ := h.Node(leaves[2], leaves[2])
+		root, _ := h.Node(ab, cc)
+		path := bytes.Join([][]byte{leaves[2], ab}, nil)
+		index := []byte{1, 0}
+		if !VerifyData(name, root, data[2], path, index, 2, 3) {
+			t.Errorf("%s: leaf c does not verify", name)
+		}
+		if VerifyData(name, root, data[2], path, index, 1, 3) {
+			t.Errorf("%s: leaf c verifies at position 1", name)
+		}
+		if VerifyData(name, root, data[2], path, index, 2, 5) {
+			t.Errorf("%s: leaf c verifies in a tree of 5 leaves", name)
+		}
+		other := MD5
+		if name == MD5 {
+			other = SHA256
+		}
+		if VerifyData(other, root, data[2], path, index, 2, 3) {
+			t.Errorf("%s: leaf c verifies with %s", name, other)
+		}
+	}
+	if VerifyData("sha1", nil, nil, nil, nil, 0, 1) {
+		t.Error("unknown hasher verifies")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gaochaolyf/Go/verify"
+)
+
+//ProofOrder is the order of the sibling hashes in a Proof.
+type ProofOrder int
+
+const (
+	//LeafFirst lists siblings from the leaf up to the root, as GetMerklePath does.
+	LeafFirst ProofOrder = iota
+	//RootFirst lists siblings from the root down to the leaf.
+	RootFirst
+)
+
+//String returns the name of the order.
+func (o ProofOrder) String() string {
+	switch o {
+	case LeafFirst:
+		return "leaf-first"
+	case RootFirst:
+		return "root-first"
+	}
+	return fmt.Sprintf("ProofOrder(%d)", int(o))
+}
+
+//MarshalText encodes the order by name, so JSON proofs state their order explicitly.
+func (o ProofOrder) MarshalText() ([]byte, error) {
+	if o != LeafFirst && o != RootFirst {
+		return nil, fmt.Errorf("error: invalid proof order %d", int(o))
+	}
+	return []byte(o.String()), nil
+}
+
+//UnmarshalText decodes an order encoded by MarshalText.
+func (o *ProofOrder) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "leaf-first":
+		*o = LeafFirst
+	case "root-first":
+		*o = RootFirst
+	default:
+		return fmt.Errorf("error: invalid proof order %q", b)
+	}
+	return nil
+}
+
+//Proof is a Merkle path together with the order of its siblings. Index[i] is 1 if
+//Path[i] is the right child and 0 if it is the left one. Leaf is the position of the
+//proven leaf in a tree of Size leaves.
+type Proof struct {
+	Version int        `json:"version,omitempty" jsonschema:"minimum=1"`
+	Order   ProofOrder `json:"order"`
+	Leaf    int        `json:"leaf" jsonschema:"minimum=0"`
+	Size    int        `json:"size" jsonschema:"minimum=1"`
+	Path    [][]byte   `json:"path"`
+	Index   []int64    `json:"index" jsonschema:"enum=0,1"`
+}
+
+//UnmarshalJSON decodes a proof of any version up to ProofVersion.
+func (p *Proof) UnmarshalJSON(b []byte) error {
+	type proof Proof
+	return unmarshalVersioned(b, (*proof)(p), func(v *proof) *int { return &v.Version }, "proof", ProofVersion)
+}
+
+//WithProofOrder returns an Option that sets the order of the proofs returned by GetProof.
+//The default is LeafFirst. NewTree fails if order is neither LeafFirst nor RootFirst.
+func WithProofOrder(order ProofOrder) Option {
+	return func(t *MerkleTree) error {
+		if order != LeafFirst && order != RootFirst {
+			return fmt.Errorf("error: invalid proof order %d", int(order))
+		}
+		t.proofOrder = order
+		return nil
+	}
+}
+
+//GetProof returns the proof of content in the order configured with WithProofOrder.
+func (m *MerkleTree) GetProof(content Content) (*Proof, error) {
+	for i, l := range m.Leafs {
+		ok, err := l.C.Equals(content)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			path, index := l.merklePath()
+			p := &Proof{Version: ProofVersion, Order: LeafFirst, Leaf: i, Size: m.leafCount(), Path: path, Index: index}
+			return p.InOrder(m.proofOrder), nil
+		}
+	}
+	return nil, errors.New("error: content is not part of the tree")
+}
+
+//InOrder returns a copy of the proof with its siblings in the given order.
+func (p *Proof) InOrder(order ProofOrder) *Proof {
+	q := &Proof{
+		Version: p.Version,
+		Order:   order,
+		Leaf:    p.Leaf,
+		Size:    p.Size,
+		Path:    append([][]byte(nil), p.Path...),
+		Index:   append([]int64(nil), p.Index...),
+	}
+	if order != p.Order {
+		for i, j := 0, len(q.Path)-1; i < j; i, j = i+1, j-1 {
+			q.Path[i], q.Path[j] = q.Path[j], q.Path[i]
+		}
+		for i, j := 0, len(q.Index)-1; i < j; i, j = i+1, j-1 {
+			q.Index[i], q.Index[j] = q.Index[j], q.Index[i]
+		}
+	}
+	return q
+}
+
+//LeafFirst returns a copy of the proof ordered from the leaf up to the root.
+func (p *Proof) LeafFirst() *Proof {
+	return p.InOrder(LeafFirst)
+}
+
+//RootFirst returns a copy of the proof ordered from the root down to the leaf.
+func (p *Proof) RootFirst() *Proof {
+	return p.InOrder(RootFirst)
+}
+
+//Verify reports whether the proof leads from leafHash to root, whatever its order, in a
+//tree of size leaves built with the default hash strategy. The size must be pinned with
+//the root: the path is only accepted as the one of position Leaf in a tree of that size.
+func (p *Proof) Verify(root, leafHash []byte, size int) (bool, error) {
+	return p.VerifyWith(verify.Hasher{New: defaultHashStrategy}, root, leafHash, size)
+}
+
+//VerifyWith is like Verify for a tree hashed with h.
+func (p *Proof) VerifyWith(h verify.Hasher, root, leafHash []byte, size int) (bool, error) {
+	if p.Order != LeafFirst && p.Order != RootFirst {
+		return false, fmt.Errorf("error: invalid proof order %d", int(p.Order))
+	}
+	if p.Size != size {
+		return false, fmt.Errorf("error: proof is for a tree of %d leaves, root has %d", p.Size, size)
+	}
+	q := p.LeafFirst()
+	return h.VerifyPathAt(root, leafHash, q.Path, q.Index, p.Leaf, p.Size)
+}
+
+//shortHash returns the first bytes of h in hex for debug output.
+func shortHash(h []byte) string {
+	if len(h) > 4 {
+		return fmt.Sprintf("%x...", h[:4])
+	}
+	return fmt.Sprintf("%x", h)
+}
+
+//String returns a multi-line description of the proof with truncated hashes. Levels are
+//counted from the leaves, so level 0 is the sibling of the leaf in either order.
+func (p *Proof) String() string {
+	return p.format(shortHash)
+}
+
+//Format implements fmt.Formatter. %v and %s print String, %+v prints full hashes.
+func (p *Proof) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('+'):
+		fmt.Fprint(f, p.format(func(h []byte) string { return fmt.Sprintf("%x", h) }))
+	case verb == 'v' || verb == 's':
+		fmt.Fprint(f, p.String())
+	default:
+		fmt.Fprintf(f, "%%!%c(*main.Proof)", verb)
+	}
+}
+
+//format describes the proof, printing each sibling hash with hashString.
+func (p *Proof) format(hashString func([]byte) string) string {
+	s := fmt.Sprintf("proof of leaf %d of %d (%s, %d levels)\n", p.Leaf, p.Size, p.Order, len(p.Path))
+	for i, h := range p.Path {
+		level := i
+		if p.Order == RootFirst {
+			level = len(p.Path) - 1 - i
+		}
+		dir := "?"
+		if i < len(p.Index) {
+			switch p.Index[i] {
+			case 0:
+				dir = "left"
+			case 1:
+				dir = "right"
+			}
+		}
+		s += fmt.Sprintf("  level %d: %s sibling %s\n", level, dir, hashString(h))
+	}
+	return s
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/md5"
+	"testing"
+)
+
+func TestProofVerify(t *testing.T) {
+	for n := 1; n <= 7; n++ {
+		m := iterTree(t, n)
+		for _, order := range []ProofOrder{LeafFirst, RootFirst} {
+			for i := 0; i < n; i++ {
+				c := m.Leafs[i].C
+				p, err := m.GetProof(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if p.Leaf != i || p.Size != n {
+					t.Fatalf("proof of leaf %d of %d is for leaf %d of %d", i, n, p.Leaf, p.Size)
+				}
+				q := p.InOrder(order)
+				leafHash, err := c.CalculateHash()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok, err := q.Verify(m.MerkleRoot(), leafHash, n); err != nil || !ok {
+					t.Errorf("%s proof of leaf %d of %d: %v %v", order, i, n, ok, err)
+				}
+				if ok, _ := q.Verify(m.MerkleRoot(), leafHash, n+1); ok {
+					t.Errorf("%s proof of leaf %d of %d verifies with the wrong size", order, i, n)
+				}
+			}
+		}
+	}
+	if _, err := NewTree([]Content{TestContent{x: "x"}}, WithProofOrder(ProofOrder(2))); err == nil {
+		t.Error("invalid proof order accepted")
+	}
+}
+
+func TestProofRejectsInteriorNode(t *testing.T) {
+	m := iterTree(t, 4)
+	p, err := m.GetProof(m.Leafs[0].C)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the parent of leaves 0 and 1, passed off as a leaf whose content is its children
+	forged := md5.Sum(append(append([]byte(nil), m.Leafs[0].Hash...), m.Leafs[1].Hash...))
+	if ok, _ := m.Hasher().VerifyPath(m.MerkleRoot(), forged[:], p.Path[1:], p.Index[1:]); !ok {
+		t.Fatal("forged path does not reach the root, test is broken")
+	}
+	for leaf := 0; leaf < 4; leaf++ {
+		for size := 1; size <= 4; size++ {
+			f := &Proof{Order: LeafFirst, Leaf: leaf, Size: size, Path: p.Path[1:], Index: p.Index[1:]}
+			if ok, _ := f.Verify(m.MerkleRoot(), forged[:], 4); ok {
+				t.Errorf("forged proof at leaf %d of %d verifies", leaf, size)
+			}
+		}
+	}
+}
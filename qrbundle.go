@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/gaochaolyf/Go/verify"
+)
+
+//ProofBundle returns the bundle proving c. The leaf of c must be the hash of its raw (or,
+//with WithCanonicalizer, canonical) bytes under the tree's Hasher, since that is how a
+//verifier recomputes it from Data. The bundle only verifies against the root together
+//with the number of leaves of the tree, so both must be published.
+func (m *MerkleTree) ProofBundle(c RawContent) (*verify.ProofBundle, error) {
+	data, err := c.Raw()
+	if err != nil {
+		return nil, err
+	}
+	if m.canonicalizer != nil {
+		if data, err = m.canonicalizer(data); err != nil {
+			return nil, err
+		}
+	}
+	dataHash, err := m.hasher.Leaf(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range m.Leafs {
+		ok, err := l.C.Equals(c)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if !bytes.Equal(l.Hash, dataHash) {
+				return nil, errors.New("error: leaf hash is not the hash of the content bytes")
+			}
+			path, index := l.merklePath()
+			return &verify.ProofBundle{Leaf: i, Size: m.leafCount(), Data: data, Path: path, Index: index}, nil
+		}
+	}
+	return nil, errors.New("error: content is not part of the tree")
+}
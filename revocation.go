@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/gaochaolyf/Go/verify"
+)
+
+//RevocationRange is a leaf of a RevocationList. Low is a revoked serial and no serial
+//strictly between Low and High is revoked. A nil Low marks the range before the first
+//revoked serial and a nil High marks the range after the last one.
+type RevocationRange struct {
+	Low  *big.Int `json:"low"`
+	High *big.Int `json:"high"`
+}
+
+//bytes returns the encoding of the range that is hashed into the tree.
+func (r RevocationRange) bytes() []byte {
+	b := []byte{'r'}
+	for _, v := range []*big.Int{r.Low, r.High} {
+		if v == nil {
+			b = append(b, 0)
+			continue
+		}
+		vb := v.Bytes()
+		b = append(b, 1)
+		b = binary.AppendUvarint(b, uint64(len(vb)))
+		b = append(b, vb...)
+	}
+	return b
+}
+
+//CalculateHash hashes the encoding of a RevocationRange
+func (r RevocationRange) CalculateHash() ([]byte, error) {
+	return verify.SHA256.Leaf(r.bytes())
+}
+
+//Equals tests for equality of two RevocationRanges
+func (r RevocationRange) Equals(other Content) (bool, error) {
+	o, ok := other.(RevocationRange)
+	if !ok {
+		return false, nil
+	}
+	return bytes.Equal(r.bytes(), o.bytes()), nil
+}
+
+//covers reports whether serial is revoked by r (it equals Low) or falls strictly inside r.
+func (r RevocationRange) covers(serial *big.Int) (revoked, inside bool) {
+	if r.Low != nil {
+		switch serial.Cmp(r.Low) {
+		case 0:
+			return true, false
+		case -1:
+			return false, false
+		}
+	}
+	return false, r.High == nil || serial.Cmp(r.High) < 0
+}
+
+//RevocationList is a Merkle tree over the sorted revoked serials of a CA. Every leaf is a
+//RevocationRange between two consecutive revoked serials, so a single leaf proves either
+//that a serial is revoked or that it is not. The tree is hashed with verify.SHA256.
+type RevocationList struct {
+	serials []*big.Int
+	tree    *MerkleTree
+}
+
+//RevocationProof proves the revocation status of a serial against a RevocationList root.
+type RevocationProof struct {
+	Version int             `json:"version"`
+	Serial  *big.Int        `json:"serial"`
+	Revoked bool            `json:"revoked"`
+	Range   RevocationRange `json:"range"`
+	Path    [][]byte        `json:"path"`
+	Index   []int64         `json:"index"`
+}
+
+//UnmarshalJSON decodes a proof of any version up to RevocationProofVersion.
+func (p *RevocationProof) UnmarshalJSON(b []byte) error {
+	type revocationProof RevocationProof
+	return unmarshalVersioned(b, (*revocationProof)(p), func(v *revocationProof) *int { return &v.Version }, "revocation proof", RevocationProofVersion)
+}
+
+//RevocationDelta describes the change from one RevocationList root to the next so that
+//mirrors can update their copy without downloading the full list.
+type RevocationDelta struct {
+	Version int        `json:"version"`
+	OldRoot []byte     `json:"old_root"`
+	NewRoot []byte     `json:"new_root"`
+	Added   []*big.Int `json:"added"`
+	Removed []*big.Int `json:"removed"`
+}
+
+//UnmarshalJSON decodes a delta of any version up to RevocationDeltaVersion.
+func (d *RevocationDelta) UnmarshalJSON(b []byte) error {
+	type revocationDelta RevocationDelta
+	return unmarshalVersioned(b, (*revocationDelta)(d), func(v *revocationDelta) *int { return &v.Version }, "revocation delta", RevocationDeltaVersion)
+}
+
+//NewRevocationList creates a RevocationList from the revoked serials. Serials must not be
+//negative; duplicates are ignored.
+func NewRevocationList(serials []*big.Int) (*RevocationList, error) {
+	s, err := sortSerials(serials)
+	if err != nil {
+		return nil, err
+	}
+	r := &RevocationList{serials: s}
+	if err := r.build(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//sortSerials returns a sorted, de-duplicated copy of serials.
+func sortSerials(serials []*big.Int) ([]*big.Int, error) {
+	s := make([]*big.Int, 0, len(serials))
+	for _, v := range serials {
+		if v == nil || v.Sign() < 0 {
+			return nil, fmt.Errorf("error: invalid serial %v", v)
+		}
+		s = append(s, new(big.Int).Set(v))
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].Cmp(s[j]) < 0 })
+	out := s[:0]
+	for i, v := range s {
+		if i == 0 || v.Cmp(s[i-1]) != 0 {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+//build rebuilds the tree from the sorted serials.
+func (r *RevocationList) build() error {
+	cs := make([]Content, 0, len(r.serials)+1)
+	var low *big.Int
+	for _, v := range r.serials {
+		cs = append(cs, RevocationRange{Low: low, High: v})
+		low = v
+	}
+	cs = append(cs, RevocationRange{Low: low})
+	t, err := NewTree(cs, WithHasher(verify.SHA256))
+	if err != nil {
+		return err
+	}
+	r.tree = t
+	return nil
+}
+
+//Root returns the Merkle root of the list.
+func (r *RevocationList) Root() []byte {
+	return r.tree.MerkleRoot()
+}
+
+//Len returns the number of revoked serials.
+func (r *RevocationList) Len() int {
+	return len(r.serials)
+}
+
+//Prove returns a proof of the revocation status of serial.
+func (r *RevocationList) Prove(serial *big.Int) (*RevocationProof, error) {
+	if serial == nil || serial.Sign() < 0 {
+		return nil, fmt.Errorf("error: invalid serial %v", serial)
+	}
+	//leaf i holds the range whose High is serials[i], the last leaf has no High
+	i := sort.Search(len(r.serials), func(i int) bool { return r.serials[i].Cmp(serial) > 0 })
+	leaf := r.tree.Leafs[i]
+	rr := leaf.C.(RevocationRange)
+	revoked, _ := rr.covers(serial)
+	path, index := leaf.merklePath()
+	return &RevocationProof{
+		Version: RevocationProofVersion,
+		Serial:  new(big.Int).Set(serial),
+		Revoked: revoked,
+		Range:   rr,
+		Path:    path,
+		Index:   index,
+	}, nil
+}
+
+//Verify checks the proof against root and returns whether the serial is revoked.
+func (p *RevocationProof) Verify(root []byte) (bool, error) {
+	if p.Serial == nil {
+		return false, errors.New("error: proof has no serial")
+	}
+	revoked, inside := p.Range.covers(p.Serial)
+	if revoked != p.Revoked || (!revoked && !inside) {
+		return false, errors.New("error: range does not determine the status of the serial")
+	}
+	leafHash, err := p.Range.CalculateHash()
+	if err != nil {
+		return false, err
+	}
+	ok, err := verify.SHA256.VerifyPath(root, leafHash, p.Path, p.Index)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, errors.New("error: revocation proof does not match root")
+	}
+	return revoked, nil
+}
+
+//Apply revokes added and un-revokes removed (e.g. expired certificates), rebuilds the
+//tree and returns the delta between the old and the new root.
+func (r *RevocationList) Apply(added, removed []*big.Int) (*RevocationDelta, error) {
+	d := &RevocationDelta{Version: RevocationDeltaVersion, OldRoot: r.Root()}
+	var err error
+	if d.Added, err = sortSerials(added); err != nil {
+		return nil, err
+	}
+	if d.Removed, err = sortSerials(removed); err != nil {
+		return nil, err
+	}
+	serials, err := applySerials(r.serials, d.Added, d.Removed)
+	if err != nil {
+		return nil, err
+	}
+	next := &RevocationList{serials: serials}
+	if err := next.build(); err != nil {
+		return nil, err
+	}
+	*r = *next
+	d.NewRoot = r.Root()
+	return d, nil
+}
+
+//ApplyDelta updates a mirrored list with a delta published by the CA. The list must be at
+//the delta's old root and must reach its new root, otherwise it is left unchanged.
+func (r *RevocationList) ApplyDelta(d *RevocationDelta) error {
+	if !bytes.Equal(r.Root(), d.OldRoot) {
+		return errors.New("error: delta does not apply to the current root")
+	}
+	added, err := sortSerials(d.Added)
+	if err != nil {
+		return err
+	}
+	removed, err := sortSerials(d.Removed)
+	if err != nil {
+		return err
+	}
+	serials, err := applySerials(r.serials, added, removed)
+	if err != nil {
+		return err
+	}
+	next := &RevocationList{serials: serials}
+	if err := next.build(); err != nil {
+		return err
+	}
+	if !bytes.Equal(next.Root(), d.NewRoot) {
+		return errors.New("error: delta does not produce the announced root")
+	}
+	*r = *next
+	return nil
+}
+
+//applySerials merges the sorted added and removed serials into the sorted serials.
+//Adding a revoked serial or removing one that is not revoked is an error.
+func applySerials(serials, added, removed []*big.Int) ([]*big.Int, error) {
+	out := make([]*big.Int, 0, len(serials)+len(added))
+	i, j, k := 0, 0, 0
+	for i < len(serials) || j < len(added) {
+		switch {
+		case j == len(added) || (i < len(serials) && serials[i].Cmp(added[j]) < 0):
+			v := serials[i]
+			i++
+			if k < len(removed) && removed[k].Cmp(v) == 0 {
+				k++
+				continue
+			}
+			if k < len(removed) && removed[k].Cmp(v) < 0 {
+				return nil, fmt.Errorf("error: serial %v is not revoked", removed[k])
+			}
+			out = append(out, v)
+		case i == len(serials) || serials[i].Cmp(added[j]) > 0:
+			if k < len(removed) && removed[k].Cmp(added[j]) <= 0 {
+				return nil, fmt.Errorf("error: serial %v is not revoked", removed[k])
+			}
+			out = append(out, added[j])
+			j++
+		default:
+			return nil, fmt.Errorf("error: serial %v is already revoked", added[j])
+		}
+	}
+	if k < len(removed) {
+		return nil, fmt.Errorf("error: serial %v is not revoked", removed[k])
+	}
+	return out, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func serials(vs ...int64) []*big.Int {
+	s := make([]*big.Int, 0, len(vs))
+	for _, v := range vs {
+		s = append(s, big.NewInt(v))
+	}
+	return s
+}
+
+func TestRevocationProveVerify(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	tests := []struct {
+		name    string
+		revoked []*big.Int
+		serial  *big.Int
+		want    bool
+	}{
+		{"empty list", nil, big.NewInt(0), false},
+		{"zero before first", serials(5, 10, 20), big.NewInt(0), false},
+		{"just before first", serials(5, 10, 20), big.NewInt(4), false},
+		{"first", serials(5, 10, 20), big.NewInt(5), true},
+		{"just after first", serials(5, 10, 20), big.NewInt(6), false},
+		{"middle", serials(5, 10, 20), big.NewInt(10), true},
+		{"just before last", serials(5, 10, 20), big.NewInt(19), false},
+		{"last", serials(5, 10, 20), big.NewInt(20), true},
+		{"just after last", serials(5, 10, 20), big.NewInt(21), false},
+		{"far after last", serials(5, 10, 20), huge, false},
+		{"zero revoked", serials(0), big.NewInt(0), true},
+		{"huge revoked", []*big.Int{huge}, huge, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewRevocationList(tt.revoked)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := l.Prove(tt.serial)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.Verify(l.Root())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("revoked = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevocationProofRejectsTampering(t *testing.T) {
+	l, err := NewRevocationList(serials(5, 10, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewRevocationList(serials(5, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := l.Prove(big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Verify(other.Root()); err == nil {
+		t.Error("proof verifies against another root")
+	}
+	//the range (5, 10) says nothing about 12
+	p.Serial = big.NewInt(12)
+	if _, err := p.Verify(l.Root()); err == nil {
+		t.Error("proof verifies for a serial outside its range")
+	}
+	p, err = l.Prove(big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Revoked = false
+	if _, err := p.Verify(l.Root()); err == nil {
+		t.Error("proof of a revoked serial verifies as not revoked")
+	}
+}
+
+func TestRevocationApplyDelta(t *testing.T) {
+	ca, err := NewRevocationList(serials(5, 10, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror, err := NewRevocationList(serials(20, 10, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct{ added, removed []*big.Int }{
+		{serials(7, 30), nil},
+		{nil, serials(5)},
+		{serials(1), serials(30, 20)},
+		{serials(5, 20), serials(1, 7, 10)},
+	}
+	for i, step := range steps {
+		d, err := ca.Apply(step.added, step.removed)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if err := mirror.ApplyDelta(d); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if !bytes.Equal(mirror.Root(), ca.Root()) {
+			t.Fatalf("step %d: mirror root differs from CA root", i)
+		}
+		if err := mirror.ApplyDelta(d); err == nil {
+			t.Fatalf("step %d: delta applied twice", i)
+		}
+	}
+	want, err := NewRevocationList(serials(5, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ca.Root(), want.Root()) || ca.Len() != 2 {
+		t.Error("list after the deltas is not the expected one")
+	}
+}
+
+func TestRevocationApplyDeltaWrongNewRoot(t *testing.T) {
+	ca, err := NewRevocationList(serials(5, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror, err := NewRevocationList(serials(5, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := ca.Apply(serials(15), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Added = serials(16)
+	root := mirror.Root()
+	if err := mirror.ApplyDelta(d); err == nil {
+		t.Fatal("tampered delta applied")
+	}
+	if !bytes.Equal(mirror.Root(), root) {
+		t.Error("failed delta changed the mirror")
+	}
+}
+
+func TestApplySerialsErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		added, removed   []*big.Int
+		wantErrSubstring string
+	}{
+		{"add revoked", serials(10), nil, "serial 10 is already revoked"},
+		{"add revoked among new", serials(1, 20, 25), nil, "serial 20 is already revoked"},
+		{"remove before first", nil, serials(1), "serial 1 is not revoked"},
+		{"remove between", nil, serials(7), "serial 7 is not revoked"},
+		{"remove after last", nil, serials(30), "serial 30 is not revoked"},
+		{"remove while adding", serials(12), serials(11), "serial 11 is not revoked"},
+		{"remove what is added", serials(12), serials(12), "serial 12 is not revoked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := applySerials(serials(5, 10, 20), tt.added, tt.removed)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstring) {
+				t.Errorf("got error %v, want %q", err, tt.wantErrSubstring)
+			}
+		})
+	}
+}
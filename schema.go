@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//jsonSchemaEnum is implemented by types whose JSON encoding is one of a fixed set of
+//strings.
+type jsonSchemaEnum interface {
+	jsonSchemaEnum() []string
+}
+
+func (ProofOrder) jsonSchemaEnum() []string {
+	return []string{LeafFirst.String(), RootFirst.String()}
+}
+
+//go:generate go test -run TestProofJSONSchema -update
+
+//ProofJSONSchema returns the JSON Schema of the JSON encoding of Proof. It is generated
+//from the Go types, and proof.schema.json is its output; TestProofJSONSchema fails when
+//they differ and go generate rewrites the file.
+func ProofJSONSchema() ([]byte, error) {
+	s, err := jsonSchema(reflect.TypeOf(Proof{}))
+	if err != nil {
+		return nil, err
+	}
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = "Proof"
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+//jsonSchema returns the schema of the JSON encoding of values of type t, following the
+//rules of encoding/json for the kinds used by the package's types. A struct field tagged
+//jsonschema:"enum=0,1" only allows the listed integers and one tagged
+//jsonschema:"minimum=1" none below the bound; on a slice the tag applies to its items.
+func jsonSchema(t reflect.Type) (map[string]interface{}, error) {
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		s := map[string]interface{}{"type": "string"}
+		if e, ok := reflect.Zero(t).Interface().(jsonSchemaEnum); ok {
+			s["enum"] = e.jsonSchemaEnum()
+		}
+		return s, nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := jsonSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Struct:
+		props := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			s, err := jsonSchema(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("error: field %s: %v", f.Name, err)
+			}
+			if err := applySchemaTag(s, f.Tag.Get("jsonschema")); err != nil {
+				return nil, fmt.Errorf("error: field %s: %v", f.Name, err)
+			}
+			props[name] = s
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           props,
+			"required":             required,
+			"additionalProperties": false,
+		}, nil
+	}
+	return nil, fmt.Errorf("error: no JSON Schema for type %s", t)
+}
+
+//applySchemaTag restricts the schema s of a field with its jsonschema tag.
+func applySchemaTag(s map[string]interface{}, tag string) error {
+	if tag == "" {
+		return nil
+	}
+	key, values, ok := strings.Cut(tag, "=")
+	if !ok || (key != "enum" && key != "minimum") {
+		return fmt.Errorf("error: invalid jsonschema tag %q", tag)
+	}
+	for s["type"] == "array" {
+		s = s["items"].(map[string]interface{})
+	}
+	if s["type"] != "integer" {
+		return fmt.Errorf("error: %s tag on %v", key, s["type"])
+	}
+	var ns []int
+	for _, v := range strings.Split(values, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("error: invalid jsonschema tag %q", tag)
+		}
+		ns = append(ns, n)
+	}
+	if key == "enum" {
+		s["enum"] = ns
+		return nil
+	}
+	if len(ns) != 1 {
+		return fmt.Errorf("error: invalid jsonschema tag %q", tag)
+	}
+	s["minimum"] = ns[0]
+	return nil
+}
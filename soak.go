@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//soakConfig configures a soak run.
+type soakConfig struct {
+	Duration  time.Duration
+	Seed      int64
+	Mix       map[string]int
+	MaxLeaves int
+	Report    time.Duration
+}
+
+//soakOps are the operations of a soak workload, in the order they are drawn.
+var soakOps = []string{"append", "update", "proof", "verify"}
+
+//parseSoakMix parses weights such as "append=1,update=1,proof=4,verify=4".
+func parseSoakMix(s string) (map[string]int, error) {
+	mix := make(map[string]int)
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			return nil, fmt.Errorf("error: invalid mix entry %q", kv)
+		}
+		w, err := strconv.Atoi(v)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("error: invalid weight for %q", k)
+		}
+		known := false
+		for _, op := range soakOps {
+			known = known || op == k
+		}
+		if !known {
+			return nil, fmt.Errorf("error: unknown operation %q", k)
+		}
+		mix[k] = w
+	}
+	return mix, nil
+}
+
+//soakProof is a proof handed out during a soak run, kept to verify later.
+type soakProof struct {
+	root     []byte
+	size     int
+	leafHash []byte
+	proof    *Proof
+}
+
+//soak is the state of a soak run.
+type soak struct {
+	cfg    soakConfig
+	rng    *rand.Rand
+	items  []Content
+	tree   *MerkleTree
+	proofs []soakProof
+	next   int
+	counts map[string]int
+}
+
+//runSoak drives the configured mix of operations against a tree until the duration has
+//elapsed, checking the tree's invariants after every operation that changes it. It
+//returns the first invariant violation.
+func runSoak(cfg soakConfig) error {
+	total := 0
+	for _, op := range soakOps {
+		total += cfg.Mix[op]
+	}
+	if total == 0 {
+		return errors.New("error: soak mix has no operations")
+	}
+	if cfg.MaxLeaves < 1 {
+		return errors.New("error: soak needs at least one leaf")
+	}
+	s := &soak{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed)), counts: make(map[string]int)}
+	if err := s.append(); err != nil {
+		return err
+	}
+	start := time.Now()
+	lastReport := start
+	for time.Since(start) < cfg.Duration {
+		w := s.rng.Intn(total)
+		var op string
+		for _, op = range soakOps {
+			if w < cfg.Mix[op] {
+				break
+			}
+			w -= cfg.Mix[op]
+		}
+		var err error
+		switch op {
+		case "append":
+			if len(s.items) < cfg.MaxLeaves {
+				err = s.append()
+				break
+			}
+			op = "update"
+			fallthrough
+		case "update":
+			err = s.update()
+		case "proof":
+			err = s.proof()
+		case "verify":
+			err = s.verify()
+		}
+		if err != nil {
+			return fmt.Errorf("error: soak %s after %v: %v", op, time.Since(start).Round(time.Second), err)
+		}
+		s.counts[op]++
+		if cfg.Report > 0 && time.Since(lastReport) >= cfg.Report {
+			lastReport = time.Now()
+			log.Printf("soak: %v leaves=%d ops=%v", time.Since(start).Round(time.Second), len(s.items), s.counts)
+		}
+	}
+	log.Printf("soak: done after %v leaves=%d ops=%v", time.Since(start).Round(time.Second), len(s.items), s.counts)
+	return nil
+}
+
+//newItem returns content that differs from every item handed out before.
+func (s *soak) newItem() Content {
+	s.next++
+	return TestContent{x: fmt.Sprintf("soak-%d-%d", s.next, s.rng.Int63())}
+}
+
+//append adds a new item and rebuilds the tree.
+func (s *soak) append() error {
+	s.items = append(s.items, s.newItem())
+	return s.rebuild()
+}
+
+//update replaces a random item and rebuilds the tree.
+func (s *soak) update() error {
+	s.items[s.rng.Intn(len(s.items))] = s.newItem()
+	return s.rebuild()
+}
+
+//rebuild builds the tree over the current items and checks its invariants.
+func (s *soak) rebuild() error {
+	t, err := NewTree(s.items, WithProofOrder(ProofOrder(s.rng.Intn(2))))
+	if err != nil {
+		return err
+	}
+	s.tree = t
+	return s.check()
+}
+
+//check verifies the invariants of the current tree: it has one leaf per item, its root
+//is reproducible, the hashes of every visited node match their children, and a proof of
+//a random item verifies while a proof for a different leaf does not.
+func (s *soak) check() error {
+	if n := s.tree.leafCount(); n != len(s.items) {
+		return fmt.Errorf("tree has %d leaves for %d items", n, len(s.items))
+	}
+	again, err := NewTree(s.items)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(again.MerkleRoot(), s.tree.MerkleRoot()) {
+		return errors.New("root is not reproducible")
+	}
+	for n := range s.tree.All() {
+		if n.Left == nil {
+			continue
+		}
+		h, err := s.tree.Hasher().Node(n.Left.Hash, n.Right.Hash)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(h, n.Hash) {
+			return fmt.Errorf("node %x does not match its children", n.Hash)
+		}
+	}
+	i := s.rng.Intn(len(s.items))
+	p, err := s.tree.GetProof(s.items[i])
+	if err != nil {
+		return err
+	}
+	leafHash, err := s.items[i].CalculateHash()
+	if err != nil {
+		return err
+	}
+	if ok, err := s.tree.VerifyProof(leafHash, p); err != nil || !ok {
+		return fmt.Errorf("proof of leaf %d does not verify: %v", i, err)
+	}
+	if len(s.items) > 1 {
+		other, err := s.items[(i+1)%len(s.items)].CalculateHash()
+		if err != nil {
+			return err
+		}
+		if ok, _ := p.Verify(s.tree.MerkleRoot(), other, len(s.items)); ok {
+			return fmt.Errorf("proof of leaf %d verifies for another leaf", i)
+		}
+	}
+	return nil
+}
+
+//proof generates a proof of a random item and keeps it for later verification.
+func (s *soak) proof() error {
+	i := s.rng.Intn(len(s.items))
+	p, err := s.tree.GetProof(s.items[i])
+	if err != nil {
+		return err
+	}
+	leafHash, err := s.items[i].CalculateHash()
+	if err != nil {
+		return err
+	}
+	if len(s.proofs) == 1024 {
+		s.proofs = s.proofs[1:]
+	}
+	s.proofs = append(s.proofs, soakProof{root: s.tree.MerkleRoot(), size: len(s.items), leafHash: leafHash, proof: p})
+	return nil
+}
+
+//verify checks a proof handed out earlier against the root it was issued for, in both
+//orders.
+func (s *soak) verify() error {
+	if len(s.proofs) == 0 {
+		return s.proof()
+	}
+	sp := s.proofs[s.rng.Intn(len(s.proofs))]
+	for _, p := range []*Proof{sp.proof, sp.proof.LeafFirst(), sp.proof.RootFirst()} {
+		ok, err := p.Verify(sp.root, sp.leafHash, sp.size)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("proof issued for root %x no longer verifies", sp.root)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSoakMix(t *testing.T) {
+	mix, err := parseSoakMix("append=1, update=0,proof=4,verify=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mix["append"] != 1 || mix["update"] != 0 || mix["proof"] != 4 || mix["verify"] != 4 {
+		t.Errorf("mix = %v", mix)
+	}
+	for _, s := range []string{"append", "append=x", "append=-1", "delete=1", ""} {
+		if _, err := parseSoakMix(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestRunSoak(t *testing.T) {
+	mix, err := parseSoakMix("append=2,update=1,proof=2,verify=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := soakConfig{Duration: 200 * time.Millisecond, Seed: 1, Mix: mix, MaxLeaves: 64}
+	if err := runSoak(cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Mix = map[string]int{"append": 0}
+	if err := runSoak(cfg); err == nil {
+		t.Error("soak without operations ran")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/gaochaolyf/Go/verify"
+)
+
+//verifiedReader returns the content of a file only after each chunk has been checked
+//against the chunk hashes committed to by a ManifestEntry.
+type verifiedReader struct {
+	r      io.Reader
+	e      ManifestEntry
+	chunks [][]byte
+	next   int
+	chunk  []byte
+	buf    []byte
+	err    error
+}
+
+//NewVerifiedReader returns a reader over r, the content of the file described by e,
+//that verifies every chunk against chunks before returning any of its bytes. chunks are
+//the hashes returned by CommitFileChunks; they are checked against e.Root up front. A
+//corrupted chunk fails the read as soon as it is reached, so a training job never
+//consumes bytes that are not part of the manifest.
+func NewVerifiedReader(r io.Reader, e ManifestEntry, chunks [][]byte) (io.Reader, error) {
+	if e.ChunkSize <= 0 || e.Size < 0 {
+		return nil, fmt.Errorf("error: %s: invalid manifest entry", e.Name)
+	}
+	want := int((e.Size + int64(e.ChunkSize) - 1) / int64(e.ChunkSize))
+	if want == 0 {
+		want = 1
+	}
+	if len(chunks) != want {
+		return nil, fmt.Errorf("error: %s: got %d chunk hashes, manifest needs %d", e.Name, len(chunks), want)
+	}
+	cs := make([]Content, 0, len(chunks))
+	for _, c := range chunks {
+		cs = append(cs, chunkHash(c))
+	}
+	t, err := NewTree(cs, WithHasher(verify.SHA256))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(t.MerkleRoot(), e.Root) {
+		return nil, fmt.Errorf("error: %s: chunk hashes do not match manifest root", e.Name)
+	}
+	return &verifiedReader{r: r, e: e, chunks: chunks}, nil
+}
+
+//OpenVerified looks up the entry named name and returns a verified reader over r for it.
+//The manifest must have been checked against its trusted root with Verify.
+func (m *Manifest) OpenVerified(name string, r io.Reader, chunks [][]byte) (io.Reader, error) {
+	e, ok := m.Entry(name)
+	if !ok {
+		return nil, fmt.Errorf("error: no manifest entry %q", name)
+	}
+	return NewVerifiedReader(r, e, chunks)
+}
+
+func (v *verifiedReader) Read(p []byte) (int, error) {
+	for len(v.buf) == 0 {
+		if v.err != nil {
+			return 0, v.err
+		}
+		v.fill()
+	}
+	n := copy(p, v.buf)
+	v.buf = v.buf[n:]
+	return n, nil
+}
+
+//fill reads and verifies the next chunk, or sets v.err once the file is exhausted or
+//found to be corrupt.
+func (v *verifiedReader) fill() {
+	if v.next == len(v.chunks) {
+		var b [1]byte
+		if n, _ := io.ReadFull(v.r, b[:]); n > 0 {
+			v.err = fmt.Errorf("error: %s: content is longer than %d bytes", v.e.Name, v.e.Size)
+			return
+		}
+		v.err = io.EOF
+		return
+	}
+	size := v.e.Size - int64(v.next)*int64(v.e.ChunkSize)
+	if size > int64(v.e.ChunkSize) {
+		size = int64(v.e.ChunkSize)
+	}
+	if v.chunk == nil {
+		//the first chunk is the largest one
+		v.chunk = make([]byte, size)
+	}
+	chunk := v.chunk[:size]
+	if _, err := io.ReadFull(v.r, chunk); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("error: %s: content is truncated in chunk %d", v.e.Name, v.next)
+		}
+		v.err = err
+		return
+	}
+	h, err := hashChunk(chunk)
+	if err != nil {
+		v.err = err
+		return
+	}
+	if !bytes.Equal(h, v.chunks[v.next]) {
+		v.err = fmt.Errorf("error: %s: chunk %d does not match manifest", v.e.Name, v.next)
+		return
+	}
+	v.buf = chunk
+	v.next++
+}
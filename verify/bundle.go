@@ -0,0 +1,249 @@
+package verify
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+//Versions of the binary encoding of a ProofBundle. Version 2 adds a Compression tag
+//after the version byte.
+const (
+	proofBundleV1 = 1
+	proofBundleV2 = 2
+)
+
+//ProofBundleVersion is the newest ProofBundle encoding this package reads and writes.
+const ProofBundleVersion = proofBundleV2
+
+//ProofBundle is a self-contained inclusion proof small enough to print as a QR code,
+//for instance on a ticket or certificate. Data is the leaf content as hashed by the
+//tree, so the verifier sees what is being proven. Leaf is the position of the leaf in a
+//tree of Size leaves. Bundles are created from a tree with its ProofBundle method.
+type ProofBundle struct {
+	Leaf  int
+	Size  int
+	Data  []byte
+	Path  [][]byte
+	Index []int64
+}
+
+//Verify checks the bundle against the published root of a tree of size leaves hashed
+//with h. The size must be published with the root: the path is only accepted as the one
+//of position Leaf in a tree of that size.
+func (b *ProofBundle) Verify(h Hasher, root []byte, size int) error {
+	if b.Size != size {
+		return fmt.Errorf("error: proof bundle is for a tree of %d leaves, root has %d", b.Size, size)
+	}
+	leafHash, err := h.Leaf(b.Data)
+	if err != nil {
+		return err
+	}
+	ok, err := h.VerifyPathAt(root, leafHash, b.Path, b.Index, b.Leaf, b.Size)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("error: proof bundle does not match root")
+	}
+	return nil
+}
+
+//Encode returns the bundle as a base45 string (RFC 9285), which uses only characters of
+//the QR alphanumeric mode. Error correction is left to the QR code itself.
+func (b *ProofBundle) Encode() (string, error) {
+	body, err := b.body()
+	if err != nil {
+		return "", err
+	}
+	return encodeBase45(append([]byte{proofBundleV1}, body...)), nil
+}
+
+//EncodeCompressed is like Encode but compresses the bundle with c and tags the
+//algorithm in the header. CompressionNone produces the same output as Encode.
+func (b *ProofBundle) EncodeCompressed(c Compression) (string, error) {
+	if c == CompressionNone {
+		return b.Encode()
+	}
+	body, err := b.body()
+	if err != nil {
+		return "", err
+	}
+	if body, err = c.compress(body); err != nil {
+		return "", err
+	}
+	return encodeBase45(append([]byte{proofBundleV2, byte(c)}, body...)), nil
+}
+
+//EncodeFor is like EncodeCompressed but only uses encodings up to maxVersion, the newest
+//version the reader supports. Readers older than version 2 get an uncompressed bundle.
+func (b *ProofBundle) EncodeFor(maxVersion int, c Compression) (string, error) {
+	if maxVersion < proofBundleV1 {
+		return "", &VersionError{Format: "proof bundle", Version: maxVersion, Supported: ProofBundleVersion}
+	}
+	if maxVersion < proofBundleV2 {
+		c = CompressionNone
+	}
+	return b.EncodeCompressed(c)
+}
+
+//body returns the binary encoding of the bundle without its header.
+func (b *ProofBundle) body() ([]byte, error) {
+	if len(b.Path) != len(b.Index) {
+		return nil, errors.New("error: merkle path and index have different lengths")
+	}
+	hashSize := 0
+	if len(b.Path) > 0 {
+		hashSize = len(b.Path[0])
+	}
+	if hashSize > 255 {
+		return nil, fmt.Errorf("error: hash size %d too large", hashSize)
+	}
+	if b.Leaf < 0 || b.Size < 0 {
+		return nil, errors.New("error: invalid proof bundle position")
+	}
+	buf := binary.AppendUvarint(nil, uint64(b.Leaf))
+	buf = binary.AppendUvarint(buf, uint64(b.Size))
+	buf = binary.AppendUvarint(buf, uint64(len(b.Data)))
+	buf = append(buf, b.Data...)
+	buf = append(buf, byte(hashSize))
+	buf = binary.AppendUvarint(buf, uint64(len(b.Path)))
+	bits := make([]byte, (len(b.Index)+7)/8)
+	for i, idx := range b.Index {
+		switch idx {
+		case 1:
+			bits[i/8] |= 1 << (i % 8)
+		case 0:
+		default:
+			return nil, fmt.Errorf("error: invalid merkle path index %d", idx)
+		}
+	}
+	buf = append(buf, bits...)
+	for _, p := range b.Path {
+		if len(p) != hashSize {
+			return nil, errors.New("error: merkle path hashes have different sizes")
+		}
+		buf = append(buf, p...)
+	}
+	return buf, nil
+}
+
+//DecodeProofBundle decodes a bundle produced by Encode or EncodeCompressed. Bundles of
+//all versions up to ProofBundleVersion are accepted; newer ones fail with a
+//*VersionError.
+func DecodeProofBundle(s string) (*ProofBundle, error) {
+	buf, err := decodeBase45(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) == 0 {
+		return nil, errors.New("error: malformed proof bundle")
+	}
+	switch buf[0] {
+	case proofBundleV1:
+		return decodeProofBundleBody(buf[1:])
+	case proofBundleV2:
+		if len(buf) < 2 {
+			return nil, errors.New("error: malformed proof bundle")
+		}
+		body, err := Compression(buf[1]).decompress(buf[2:])
+		if err != nil {
+			return nil, err
+		}
+		return decodeProofBundleBody(body)
+	case 0:
+		return nil, errors.New("error: malformed proof bundle")
+	}
+	return nil, &VersionError{Format: "proof bundle", Version: int(buf[0]), Supported: ProofBundleVersion}
+}
+
+//decodeProofBundleBody decodes the output of body.
+func decodeProofBundleBody(buf []byte) (*ProofBundle, error) {
+	var pos [2]int
+	for i := range pos {
+		v, l := binary.Uvarint(buf)
+		if l <= 0 || v > math.MaxInt32 {
+			return nil, errors.New("error: malformed proof bundle")
+		}
+		pos[i] = int(v)
+		buf = buf[l:]
+	}
+	n, l := binary.Uvarint(buf)
+	if l <= 0 || n > uint64(len(buf)-l) {
+		return nil, errors.New("error: malformed proof bundle")
+	}
+	b := &ProofBundle{Leaf: pos[0], Size: pos[1], Data: append([]byte(nil), buf[l:l+int(n)]...)}
+	buf = buf[l+int(n):]
+	if len(buf) == 0 {
+		return nil, errors.New("error: malformed proof bundle")
+	}
+	hashSize := int(buf[0])
+	depth, l := binary.Uvarint(buf[1:])
+	if l <= 0 || depth > uint64(len(buf)) {
+		return nil, errors.New("error: malformed proof bundle")
+	}
+	buf = buf[1+l:]
+	nbits := (int(depth) + 7) / 8
+	if len(buf) != nbits+int(depth)*hashSize {
+		return nil, errors.New("error: malformed proof bundle")
+	}
+	bits, hashes := buf[:nbits], buf[nbits:]
+	for i := 0; i < int(depth); i++ {
+		b.Index = append(b.Index, int64(bits[i/8]>>(i%8)&1))
+		b.Path = append(b.Path, append([]byte(nil), hashes[i*hashSize:(i+1)*hashSize]...))
+	}
+	return b, nil
+}
+
+const base45Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+//encodeBase45 encodes b as described in RFC 9285.
+func encodeBase45(b []byte) string {
+	var sb strings.Builder
+	for i := 0; i+1 < len(b); i += 2 {
+		n := int(b[i])<<8 | int(b[i+1])
+		sb.WriteByte(base45Alphabet[n%45])
+		sb.WriteByte(base45Alphabet[n/45%45])
+		sb.WriteByte(base45Alphabet[n/(45*45)])
+	}
+	if len(b)%2 == 1 {
+		n := int(b[len(b)-1])
+		sb.WriteByte(base45Alphabet[n%45])
+		sb.WriteByte(base45Alphabet[n/45])
+	}
+	return sb.String()
+}
+
+//decodeBase45 decodes a string produced by encodeBase45.
+func decodeBase45(s string) ([]byte, error) {
+	if len(s)%3 == 1 {
+		return nil, errors.New("error: invalid base45 length")
+	}
+	vals := make([]int, len(s))
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(base45Alphabet, s[i])
+		if v < 0 {
+			return nil, fmt.Errorf("error: invalid base45 character %q", s[i])
+		}
+		vals[i] = v
+	}
+	out := make([]byte, 0, len(s)/3*2+1)
+	for i := 0; i < len(vals); i += 3 {
+		if i+2 < len(vals) {
+			n := vals[i] + vals[i+1]*45 + vals[i+2]*45*45
+			if n > 0xffff {
+				return nil, errors.New("error: invalid base45 triplet")
+			}
+			out = append(out, byte(n>>8), byte(n))
+			continue
+		}
+		n := vals[i] + vals[i+1]*45
+		if n > 0xff {
+			return nil, errors.New("error: invalid base45 pair")
+		}
+		out = append(out, byte(n))
+	}
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package verify
+
+import (
+	"fmt"
+	"testing"
+)
+
+//testTree returns the root and the leaf-first paths of a tree over data built like the
+//trees of the main package: an odd node at any level is paired with itself.
+func testTree(t *testing.T, h Hasher, data [][]byte) ([]byte, [][][]byte, [][]int64) {
+	t.Helper()
+	level := make([][]byte, len(data))
+	for i, d := range data {
+		var err error
+		if level[i], err = h.Leaf(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := make([][][]byte, len(data))
+	indexes := make([][]int64, len(data))
+	pos := make([]int, len(data))
+	for i := range pos {
+		pos[i] = i
+	}
+	for len(level) > 1 || len(paths[0]) == 0 {
+		for i, p := range pos {
+			if p%2 == 0 {
+				sibling := p + 1
+				if sibling == len(level) {
+					sibling = p
+				}
+				paths[i] = append(paths[i], level[sibling])
+				indexes[i] = append(indexes[i], 1)
+			} else {
+				paths[i] = append(paths[i], level[p-1])
+				indexes[i] = append(indexes[i], 0)
+			}
+			pos[i] = p / 2
+		}
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			right := i + 1
+			if right == len(level) {
+				right = i
+			}
+			n, err := h.Node(level[i], level[right])
+			if err != nil {
+				t.Fatal(err)
+			}
+			next = append(next, n)
+		}
+		level = next
+	}
+	return level[0], paths, indexes
+}
+
+func TestProofBundleRoundTrip(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		var data [][]byte
+		for i := 0; i < size; i++ {
+			data = append(data, []byte(fmt.Sprintf("leaf %d", i)))
+		}
+		root, paths, indexes := testTree(t, MD5, data)
+		for i := range data {
+			b := &ProofBundle{Leaf: i, Size: size, Data: data[i], Path: paths[i], Index: indexes[i]}
+			for _, c := range []Compression{CompressionNone, CompressionDeflate} {
+				s, err := b.EncodeCompressed(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := DecodeProofBundle(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := got.Verify(MD5, root, size); err != nil {
+					t.Errorf("size %d leaf %d: %v", size, i, err)
+				}
+				if err := got.Verify(MD5, root, size+1); err == nil {
+					t.Errorf("size %d leaf %d: verified with the wrong size", size, i)
+				}
+			}
+		}
+	}
+}
+
+func TestProofBundleRejectsInteriorNode(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")}
+	root, paths, indexes := testTree(t, MD5, data)
+	la, err := MD5.Leaf(data[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb, err := MD5.Leaf(data[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the parent of a and b, passed off as a leaf whose content is its children
+	forged := &ProofBundle{
+		Data:  append(append([]byte(nil), la...), lb...),
+		Path:  paths[0][1:],
+		Index: indexes[0][1:],
+	}
+	if ok, _ := MD5.VerifyPath(root, mustLeaf(t, forged.Data), forged.Path, forged.Index); !ok {
+		t.Fatal("forged path does not reach the root, test is broken")
+	}
+	for leaf := 0; leaf < 4; leaf++ {
+		for size := 1; size <= 4; size++ {
+			forged.Leaf, forged.Size = leaf, size
+			if err := forged.Verify(MD5, root, 4); err == nil {
+				t.Errorf("forged bundle at leaf %d of %d verifies", leaf, size)
+			}
+		}
+	}
+}
+
+func mustLeaf(t *testing.T, data []byte) []byte {
+	t.Helper()
+	h, err := MD5.Leaf(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
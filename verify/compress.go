@@ -0,0 +1,111 @@
+package verify
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"sync"
+)
+
+//Compression identifies the compression algorithm of an encoding. It is stored as a
+//single byte in the encoding's header.
+type Compression byte
+
+//Compression algorithms. Only CompressionNone and CompressionDeflate are built in; the
+//zstd and snappy tags are reserved for implementations registered with
+//RegisterCompression, since they live outside the standard library.
+const (
+	CompressionNone    Compression = 0
+	CompressionDeflate Compression = 1
+	CompressionZstd    Compression = 2
+	CompressionSnappy  Compression = 3
+)
+
+//maxDecompressedSize bounds the output of the built-in decompressors.
+const maxDecompressedSize = 64 << 20
+
+type compressor struct {
+	compress   func([]byte) ([]byte, error)
+	decompress func([]byte) ([]byte, error)
+}
+
+var (
+	compressorsMu sync.RWMutex
+	compressors   = map[Compression]compressor{
+		CompressionDeflate: {compress: deflate, decompress: inflate},
+	}
+)
+
+//RegisterCompression registers the implementation of algorithm c, replacing any
+//previous one. Decompress should bound the size of its output.
+func RegisterCompression(c Compression, compress, decompress func([]byte) ([]byte, error)) {
+	compressorsMu.Lock()
+	defer compressorsMu.Unlock()
+	compressors[c] = compressor{compress: compress, decompress: decompress}
+}
+
+//lookup returns the registered implementation of c.
+func (c Compression) lookup() (compressor, error) {
+	compressorsMu.RLock()
+	defer compressorsMu.RUnlock()
+	cc, ok := compressors[c]
+	if !ok {
+		return compressor{}, fmt.Errorf("error: compression %d is not registered", byte(c))
+	}
+	return cc, nil
+}
+
+//compress compresses b with c.
+func (c Compression) compress(b []byte) ([]byte, error) {
+	if c == CompressionNone {
+		return b, nil
+	}
+	cc, err := c.lookup()
+	if err != nil {
+		return nil, err
+	}
+	return cc.compress(b)
+}
+
+//decompress decompresses b with c.
+func (c Compression) decompress(b []byte) ([]byte, error) {
+	if c == CompressionNone {
+		return b, nil
+	}
+	cc, err := c.lookup()
+	if err != nil {
+		return nil, err
+	}
+	return cc.decompress(b)
+}
+
+//deflate is the built-in CompressionDeflate compressor.
+func deflate(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+//inflate is the built-in CompressionDeflate decompressor.
+func inflate(b []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(b))
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxDecompressedSize {
+		return nil, fmt.Errorf("error: decompressed data exceeds %d bytes", maxDecompressedSize)
+	}
+	return out, nil
+}
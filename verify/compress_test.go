@@ -0,0 +1,40 @@
+package verify
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeCompressedUnregistered(t *testing.T) {
+	b := &ProofBundle{Leaf: 0, Size: 1, Data: []byte("data")}
+	for _, c := range []Compression{CompressionZstd, CompressionSnappy, 200} {
+		if _, err := b.EncodeCompressed(c); err == nil {
+			t.Errorf("compression %d: no error", c)
+		}
+	}
+	//a bundle tagged with an unregistered algorithm does not decode either
+	body, err := b.body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := encodeBase45(append([]byte{proofBundleV2, byte(CompressionZstd)}, body...))
+	if _, err := DecodeProofBundle(s); err == nil {
+		t.Error("zstd bundle decoded without a zstd implementation")
+	}
+}
+
+func TestInflateLimit(t *testing.T) {
+	for _, n := range []int{maxDecompressedSize, maxDecompressedSize + 1} {
+		z, err := deflate(make([]byte, n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := inflate(z)
+		switch {
+		case n <= maxDecompressedSize && (err != nil || !bytes.Equal(out, make([]byte, n))):
+			t.Errorf("%d bytes: %v", n, err)
+		case n > maxDecompressedSize && err == nil:
+			t.Errorf("%d bytes: inflated past the limit", n)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+//Package verify checks Merkle paths and proof bundles against a pinned root. It does not
+//build trees, so verifiers such as mobile apps can depend on it alone.
+package verify
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+//Hasher describes how a tree hashes its leaves and combines child hashes.
+type Hasher struct {
+	//New returns the hash of the tree.
+	New func() hash.Hash
+	//LeafPrefix and NodePrefix are written before the input of leaf and node hashes.
+	//Distinct prefixes keep a leaf from being passed off as an intermediate node.
+	LeafPrefix []byte
+	NodePrefix []byte
+}
+
+//MD5 is the Hasher of trees built with the default hash strategy.
+var MD5 = Hasher{New: md5.New}
+
+//SHA256 hashes with SHA-256 and separates leaves from nodes with the 0x00 and 0x01
+//prefixes of RFC 6962. It is used by the trees of commitments that must stand up to an
+//adversary able to find MD5 collisions.
+var SHA256 = Hasher{New: sha256.New, LeafPrefix: []byte{0}, NodePrefix: []byte{1}}
+
+//Leaf returns the leaf hash of data.
+func (h Hasher) Leaf(data []byte) ([]byte, error) {
+	d := h.New()
+	if _, err := d.Write(h.LeafPrefix); err != nil {
+		return nil, err
+	}
+	if _, err := d.Write(data); err != nil {
+		return nil, err
+	}
+	return d.Sum(nil), nil
+}
+
+//Node returns the hash of the parent of left and right.
+func (h Hasher) Node(left, right []byte) ([]byte, error) {
+	d := h.New()
+	if _, err := d.Write(h.NodePrefix); err != nil {
+		return nil, err
+	}
+	if _, err := d.Write(left); err != nil {
+		return nil, err
+	}
+	if _, err := d.Write(right); err != nil {
+		return nil, err
+	}
+	return d.Sum(nil), nil
+}
+
+//Root recomputes the root from leafHash and a Merkle path ordered from the leaf up to the
+//root. index[i] is 1 if path[i] is the right child and 0 if it is the left one.
+func (h Hasher) Root(leafHash []byte, path [][]byte, index []int64) ([]byte, error) {
+	return h.root(leafHash, path, index, nil)
+}
+
+//root is Root calling check, if not nil, with every level before hashing it.
+func (h Hasher) root(leafHash []byte, path [][]byte, index []int64, check func(level int, current, sibling []byte) error) ([]byte, error) {
+	if len(path) != len(index) {
+		return nil, errors.New("error: merkle path and index have different lengths")
+	}
+	current := leafHash
+	for i, sibling := range path {
+		if check != nil {
+			if err := check(i, current, sibling); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		switch index[i] {
+		case 1:
+			current, err = h.Node(current, sibling)
+		case 0:
+			current, err = h.Node(sibling, current)
+		default:
+			return nil, fmt.Errorf("error: invalid merkle path index %d", index[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return current, nil
+}
+
+//VerifyPath reports whether path leads from leafHash to root.
+func (h Hasher) VerifyPath(root, leafHash []byte, path [][]byte, index []int64) (bool, error) {
+	current, err := h.Root(leafHash, path, index)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(current, root), nil
+}
+
+//Depth returns the length of the Merkle paths of a tree with size leaves. Every leaf of
+//such a tree is at the same depth, since an odd node at any level is paired with itself.
+func Depth(size int) int {
+	d := 0
+	for w := size; w > 1 || d == 0; w = (w + 1) / 2 {
+		d++
+	}
+	return d
+}
+
+//VerifyPathAt is like VerifyPath but also requires path to be the one of the leaf at
+//position leaf in a tree of size leaves: it must be Depth(size) long, its indexes must
+//follow from the position and the last node of an odd level must be its own sibling.
+//Unless the Hasher separates leaves from nodes, the concatenation of two child hashes
+//could otherwise be passed off as a leaf with the rest of its path.
+func (h Hasher) VerifyPathAt(root, leafHash []byte, path [][]byte, index []int64, leaf, size int) (bool, error) {
+	if size < 1 || leaf < 0 || leaf >= size {
+		return false, fmt.Errorf("error: leaf %d out of range for a tree of %d leaves", leaf, size)
+	}
+	if len(path) != Depth(size) {
+		return false, fmt.Errorf("error: merkle path has %d levels, a tree of %d leaves has %d", len(path), size, Depth(size))
+	}
+	pos, width := leaf, size
+	current, err := h.root(leafHash, path, index, func(level int, current, sibling []byte) error {
+		want := int64(1 - pos%2)
+		if index[level] != want || (want == 1 && pos == width-1 && !bytes.Equal(current, sibling)) {
+			return fmt.Errorf("error: merkle path does not match leaf %d at level %d", leaf, level)
+		}
+		pos, width = pos/2, (width+1)/2
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(current, root), nil
+}
@@ -0,0 +1,18 @@
+package verify
+
+import "fmt"
+
+//VersionError is returned when decoding data written in a format version this package
+//does not support, typically by a newer release during a rolling upgrade. Callers can
+//detect it with errors.As and retry against an up-to-date peer instead of treating the
+//data as corrupt.
+type VersionError struct {
+	Format    string
+	Version   int
+	Supported int
+}
+
+//Error describes the unsupported version.
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("error: %s version %d is not supported, newest supported version is %d", e.Format, e.Version, e.Supported)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gaochaolyf/Go/verify"
+)
+
+//Newest JSON encodings of the formats this package reads and writes. Encodings without a
+//version predate versioning and are read as version 1.
+const (
+	ProofVersion           = 1
+	RevocationProofVersion = 1
+	RevocationDeltaVersion = 1
+	CommitmentVersion      = 1
+	RevealVersion          = 1
+	ItemRevealVersion      = 1
+	BeaconAuditVersion     = 1
+	ManifestDiffVersion    = 1
+)
+
+//checkVersion checks the decoded version *v of format against supported, the newest
+//version this package reads. A missing version is set to 1 and a negative one is
+//malformed data; only newer versions fail with a *verify.VersionError.
+func checkVersion(format string, v *int, supported int) error {
+	switch {
+	case *v == 0:
+		*v = 1
+	case *v < 0:
+		return fmt.Errorf("error: malformed %s: invalid version %d", format, *v)
+	case *v > supported:
+		return &verify.VersionError{Format: format, Version: *v, Supported: supported}
+	}
+	return nil
+}
+
+//unmarshalVersioned is the UnmarshalJSON of the versioned formats: it decodes b into *v,
+//checking the version field returned by version with checkVersion, and leaves *v
+//unchanged if either fails. T must not have the UnmarshalJSON method calling it, so each
+//format passes a type defined with its fields but not its methods:
+//
+//	func (p *Proof) UnmarshalJSON(b []byte) error {
+//		type proof Proof
+//		return unmarshalVersioned(b, (*proof)(p), func(v *proof) *int { return &v.Version }, "proof", ProofVersion)
+//	}
+func unmarshalVersioned[T any](b []byte, v *T, version func(*T) *int, format string, supported int) error {
+	var w T
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	if err := checkVersion(format, version(&w), supported); err != nil {
+		return err
+	}
+	*v = w
+	return nil
+}
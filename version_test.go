@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gaochaolyf/Go/verify"
+)
+
+func TestUnmarshalVersioned(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		wantVersion int
+		wantErr     bool
+	}{
+		{"no version", `{"order":"root-first","leaf":1,"size":2}`, 1, false},
+		{"version 1", `{"version":1,"order":"root-first","leaf":1,"size":2}`, 1, false},
+		{"newer", `{"version":2,"order":"root-first"}`, 0, true},
+		{"negative", `{"version":-1,"order":"root-first"}`, 0, true},
+		{"malformed", `{"version":"1"}`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Proof{Leaf: 7}
+			err := json.Unmarshal([]byte(tt.in), &p)
+			var verr *verify.VersionError
+			if isVersion := errors.As(err, &verr); isVersion != (tt.name == "newer") {
+				t.Errorf("got %v, VersionError %v", err, isVersion)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("no error")
+				}
+				if p.Leaf != 7 {
+					t.Error("failed decode changed the proof")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Version != tt.wantVersion || p.Order != RootFirst || p.Leaf != 1 || p.Size != 2 {
+				t.Errorf("decoded %+v", p)
+			}
+		})
+	}
+}
+
+func TestVersionedFormats(t *testing.T) {
+	formats := []json.Unmarshaler{
+		&Proof{}, &Manifest{}, &ManifestDiff{}, &RevocationProof{}, &RevocationDelta{},
+		&Commitment{}, &Reveal{}, &ItemReveal{}, &BeaconAudit{},
+	}
+	for _, v := range formats {
+		if err := json.Unmarshal([]byte(`{}`), v); err != nil {
+			t.Errorf("%T without a version: %v", v, err)
+		}
+		var verr *verify.VersionError
+		if err := json.Unmarshal([]byte(`{"version":2}`), v); !errors.As(err, &verr) {
+			t.Errorf("%T of version 2: got %v", v, err)
+		}
+	}
+}